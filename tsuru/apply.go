@@ -0,0 +1,408 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
+	"gopkg.in/yaml.v1"
+	"launchpad.net/gnuflag"
+)
+
+// appSpec is the description of an app read by app-apply. Fields that are
+// not present in the file are not managed: app-apply leaves them untouched.
+type appSpec struct {
+	Name      string            `yaml:"name"`
+	Platform  string            `yaml:"platform"`
+	Plan      string            `yaml:"plan"`
+	Pool      string            `yaml:"pool"`
+	TeamOwner string            `yaml:"teamowner"`
+	Units     map[string]int    `yaml:"units"`
+	CNames    []string          `yaml:"cnames"`
+	Env       map[string]string `yaml:"env"`
+	Services  []string          `yaml:"services"`
+}
+
+type appApply struct {
+	cmd.GuessingCommand
+	cmd.ConfirmationCommand
+	lockWaiter
+	fs    *gnuflag.FlagSet
+	file  string
+	prune bool
+}
+
+func (c *appApply) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-apply",
+		Usage: "app-apply -f/--file tsuru.yaml [-a/--app appname] [--prune] [-y/--assume-yes] [--wait-lock 5m]",
+		Desc: `Makes an app match the description in a YAML file, creating the app if it
+doesn't exist. The file looks like this:
+
+::
+
+    name: myapp
+    platform: python
+    plan: small
+    pool: pool1
+    teamowner: myteam
+    units:
+      web: 4
+      worker: 2
+    cnames:
+      - myapp.example.com
+    env:
+      DEBUG: "0"
+    services:
+      - mydb
+
+Only the fields present in the file are managed. The environment variables in
+the file are set as public variables. Public variables that are not in the
+file are kept, unless the [[--prune]] flag is used, in which case they are
+removed. Private variables, such as the ones created by service
+bindings, are never changed. The app name is taken from the file; when the
+file has no name, the [[--app]] flag or the name guessed from the current
+directory is used. The platform of an existing app can't be changed.
+
+The command compares the file with the app and prints the changes before
+asking for confirmation. Changes are applied in a safe order: things are
+created and added before anything is removed.`,
+		MinArgs: 0,
+		MaxArgs: 0,
+	}
+}
+
+func (c *appApply) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(
+			c.GuessingCommand.Flags(),
+			c.ConfirmationCommand.Flags(),
+		)
+		c.fs = cmd.MergeFlagSet(c.fs, c.lockWaiter.Flags())
+		fileMessage := "YAML file describing the app"
+		c.fs.StringVar(&c.file, "file", "", fileMessage)
+		c.fs.StringVar(&c.file, "f", "", fileMessage)
+		c.fs.BoolVar(&c.prune, "prune", false, "Unset public variables that are not in the file")
+	}
+	return c.fs
+}
+
+func (c *appApply) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	if c.file == "" {
+		return errors.New("Please use the -f/--file flag to specify the file describing the app.")
+	}
+	spec, err := readAppSpec(c.file)
+	if err != nil {
+		return err
+	}
+	appName := spec.Name
+	if appName == "" {
+		appName, err = c.Guess()
+		if err != nil {
+			return err
+		}
+	}
+	state, err := loadAppState(client, appName)
+	if err != nil {
+		return err
+	}
+	plan, err := planAppApply(appName, spec, state, c.prune)
+	if err != nil {
+		return err
+	}
+	if len(plan.changes) == 0 {
+		fmt.Fprintf(context.Stdout, "App %q is already up to date with %q.\n", appName, c.file)
+		return nil
+	}
+	fmt.Fprintf(context.Stdout, "Changes to app %q:\n", appName)
+	for _, change := range plan.changes {
+		fmt.Fprintf(context.Stdout, "  %s\n", change.description)
+	}
+	for _, name := range plan.keptEnv {
+		fmt.Fprintf(context.Stdout, "  %s is private in the app and will be left unchanged\n", name)
+	}
+	if !c.Confirm(context, fmt.Sprintf("Apply these changes to app %q?", appName)) {
+		return nil
+	}
+	if state.app != nil {
+		err = c.waitLock(context.Stdout, client, appName)
+		if err != nil {
+			return err
+		}
+	}
+	for _, change := range plan.changes {
+		fmt.Fprintf(context.Stdout, "==> %s\n", change.description)
+		err = change.apply(context, client)
+		if err != nil {
+			return fmt.Errorf("failed to apply %q: %s", change.description, err)
+		}
+	}
+	fmt.Fprintf(context.Stdout, "App %q is up to date with %q.\n", appName, c.file)
+	return nil
+}
+
+func readAppSpec(path string) (*appSpec, error) {
+	f, err := filesystem().Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("file %q doesn't exist", path)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	var spec appSpec
+	err = yaml.Unmarshal(content, &spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid app file %q: %s", path, err)
+	}
+	for process, units := range spec.Units {
+		if units < 0 {
+			return nil, fmt.Errorf("invalid app file %q: negative number of units for process %q", path, process)
+		}
+	}
+	return &spec, nil
+}
+
+// appState is the live state of an app, as seen by app-apply. app is nil
+// when the app doesn't exist.
+type appState struct {
+	app      *app
+	env      []envVar
+	services []string
+}
+
+func loadAppState(client *cmd.Client, appName string) (*appState, error) {
+	a, err := getApp(client, appName)
+	if err != nil {
+		if e, ok := err.(*tsuruErrors.HTTP); ok && e.Code == http.StatusNotFound {
+			return &appState{}, nil
+		}
+		return nil, err
+	}
+	env, err := getEnvVars(appName, client)
+	if err != nil {
+		return nil, err
+	}
+	services, err := getServiceInstances(client, appName)
+	if err != nil {
+		return nil, err
+	}
+	state := appState{app: a, env: env}
+	for _, service := range services {
+		state.services = append(state.services, service.Instances...)
+	}
+	return &state, nil
+}
+
+// applyStep orders the changes of app-apply, so things are created before
+// they are removed.
+type applyStep int
+
+const (
+	stepCreate applyStep = iota
+	stepSettings
+	stepBind
+	stepSetEnv
+	stepAddUnits
+	stepAddCNames
+	stepRemoveCNames
+	stepUnsetEnv
+	stepRemoveUnits
+	stepUnbind
+)
+
+type appChange struct {
+	step        applyStep
+	description string
+	apply       func(context *cmd.Context, client *cmd.Client) error
+}
+
+type appChanges []appChange
+
+func (l appChanges) Len() int           { return len(l) }
+func (l appChanges) Less(i, j int) bool { return l[i].step < l[j].step }
+func (l appChanges) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+type appApplyPlan struct {
+	changes appChanges
+	keptEnv []string
+}
+
+// planAppApply compares the spec with the state of the app, returning the
+// changes needed to make the app match the spec, in the order they must be
+// applied. Public variables missing from the spec are only unset when prune
+// is true.
+func planAppApply(appName string, spec *appSpec, state *appState, prune bool) (*appApplyPlan, error) {
+	var plan appApplyPlan
+	add := func(step applyStep, description string, apply func(*cmd.Context, *cmd.Client) error) {
+		plan.changes = append(plan.changes, appChange{step: step, description: description, apply: apply})
+	}
+	current := state.app
+	if current == nil {
+		if spec.Platform == "" {
+			return nil, fmt.Errorf("app %q doesn't exist, and the file doesn't define its platform", appName)
+		}
+		current = &app{Name: appName}
+		fields := []string{"platform: " + spec.Platform}
+		for _, field := range []struct{ name, value string }{
+			{"plan", spec.Plan}, {"pool", spec.Pool}, {"team owner", spec.TeamOwner},
+		} {
+			if field.value != "" {
+				fields = append(fields, field.name+": "+field.value)
+			}
+		}
+		description := fmt.Sprintf("+ create app %q (%s)", appName, strings.Join(fields, ", "))
+		add(stepCreate, description, func(context *cmd.Context, client *cmd.Client) error {
+			create := appCreate{plan: spec.Plan, teamOwner: spec.TeamOwner, pool: spec.Pool}
+			createContext := *context
+			createContext.Args = []string{appName, spec.Platform}
+			return create.Run(&createContext, client)
+		})
+	} else {
+		if spec.Platform != "" && spec.Platform != current.Platform {
+			return nil, fmt.Errorf("the platform of app %q is %q, it can't be changed to %q", appName, current.Platform, spec.Platform)
+		}
+		if spec.TeamOwner != "" && spec.TeamOwner != current.TeamOwner {
+			add(stepSettings, fmt.Sprintf("~ team owner: %s -> %s", current.TeamOwner, spec.TeamOwner), func(context *cmd.Context, client *cmd.Client) error {
+				return setTeamOwner(client, appName, spec.TeamOwner)
+			})
+		}
+		if spec.Pool != "" && spec.Pool != current.Pool {
+			add(stepSettings, fmt.Sprintf("~ pool: %s -> %s", current.Pool, spec.Pool), func(context *cmd.Context, client *cmd.Client) error {
+				return changePool(client, appName, spec.Pool)
+			})
+		}
+		if spec.Plan != "" && spec.Plan != current.Plan.Name {
+			add(stepSettings, fmt.Sprintf("~ plan: %s -> %s", current.Plan.Name, spec.Plan), func(context *cmd.Context, client *cmd.Client) error {
+				return changePlan(context.Stdout, client, appName, spec.Plan)
+			})
+		}
+	}
+	if spec.Services != nil {
+		bound := make(map[string]bool, len(state.services))
+		for _, instance := range state.services {
+			bound[instance] = true
+		}
+		wanted := make(map[string]bool, len(spec.Services))
+		for _, instance := range spec.Services {
+			wanted[instance] = true
+			if bound[instance] {
+				continue
+			}
+			instance := instance
+			add(stepBind, fmt.Sprintf("+ bind service instance %s", instance), func(context *cmd.Context, client *cmd.Client) error {
+				return bindServiceInstance(context.Stdout, client, instance, appName)
+			})
+		}
+		for _, instance := range state.services {
+			if wanted[instance] {
+				continue
+			}
+			instance := instance
+			add(stepUnbind, fmt.Sprintf("- unbind service instance %s", instance), func(context *cmd.Context, client *cmd.Client) error {
+				return unbindServiceInstance(context.Stdout, client, instance, appName)
+			})
+		}
+	}
+	if spec.Env != nil {
+		envPlan := planEnvSync(state.env, spec.Env, false, prune)
+		plan.keptEnv = envPlan.kept
+		if len(envPlan.set) > 0 {
+			add(stepSetEnv, fmt.Sprintf("~ set %d variable(s): %s", len(envPlan.set), strings.Join(sortedEnvNames(envPlan.set), ", ")), func(context *cmd.Context, client *cmd.Client) error {
+				return setEnvVars(context.Stdout, client, appName, envPlan.set, false)
+			})
+		}
+		if len(envPlan.unset) > 0 {
+			add(stepUnsetEnv, fmt.Sprintf("- unset %d variable(s): %s", len(envPlan.unset), strings.Join(envPlan.unset, ", ")), func(context *cmd.Context, client *cmd.Client) error {
+				return unsetEnvVars(context.Stdout, client, appName, envPlan.unset)
+			})
+		}
+	}
+	if spec.Units != nil {
+		counts := unitCounts(current.Units)
+		processes := make([]string, 0, len(spec.Units))
+		for process := range spec.Units {
+			processes = append(processes, process)
+		}
+		sort.Strings(processes)
+		for _, process := range processes {
+			process, delta := process, spec.Units[process]-counts[process]
+			switch {
+			case delta > 0:
+				description := fmt.Sprintf("+ units of process %s: %d -> %d", process, counts[process], spec.Units[process])
+				add(stepAddUnits, description, func(context *cmd.Context, client *cmd.Client) error {
+					return addUnits(context.Stdout, client, appName, strconv.Itoa(delta), process)
+				})
+			case delta < 0:
+				description := fmt.Sprintf("- units of process %s: %d -> %d", process, counts[process], spec.Units[process])
+				add(stepRemoveUnits, description, func(context *cmd.Context, client *cmd.Client) error {
+					return removeUnits(context.Stdout, client, appName, strconv.Itoa(-delta), process)
+				})
+			}
+		}
+	}
+	if spec.CNames != nil {
+		existing := make(map[string]bool)
+		for _, cname := range state.cnames() {
+			existing[cname] = true
+		}
+		wanted := make(map[string]bool, len(spec.CNames))
+		var toAdd, toRemove []string
+		for _, cname := range spec.CNames {
+			wanted[cname] = true
+			if !existing[cname] {
+				toAdd = append(toAdd, cname)
+			}
+		}
+		for _, cname := range state.cnames() {
+			if !wanted[cname] {
+				toRemove = append(toRemove, cname)
+			}
+		}
+		if len(toAdd) > 0 {
+			add(stepAddCNames, fmt.Sprintf("+ cname(s): %s", strings.Join(toAdd, ", ")), func(context *cmd.Context, client *cmd.Client) error {
+				return requestCName("POST", appName, toAdd, client)
+			})
+		}
+		if len(toRemove) > 0 {
+			add(stepRemoveCNames, fmt.Sprintf("- cname(s): %s", strings.Join(toRemove, ", ")), func(context *cmd.Context, client *cmd.Client) error {
+				return requestCName("DELETE", appName, toRemove, client)
+			})
+		}
+	}
+	sort.Stable(plan.changes)
+	return &plan, nil
+}
+
+func (s *appState) cnames() []string {
+	if s.app == nil {
+		return nil
+	}
+	return s.app.cnames()
+}
+
+// unitCounts returns the number of units of each process.
+func unitCounts(units []unit) map[string]int {
+	counts := make(map[string]int)
+	for _, u := range units {
+		if u.ID != "" {
+			counts[u.ProcessName]++
+		}
+	}
+	return counts
+}
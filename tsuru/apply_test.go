@@ -0,0 +1,216 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+const appApplyFile = `name: myapp
+platform: python
+plan: medium
+pool: pool1
+teamowner: team1
+units:
+  web: 3
+  worker: 1
+cnames:
+  - myapp.example.com
+  - www.example.com
+env:
+  DEBUG: "0"
+  SECRET: "x"
+services:
+  - mydb
+`
+
+func appApplyStubAPI() *stubAPI {
+	return newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","platform":"python","pool":"pool1","teamowner":"team1","plan":{"name":"small"},
+"cname":["old.example.com","myapp.example.com"],
+"units":[{"ID":"u1","ProcessName":"web","Status":"started"},{"ID":"u2","ProcessName":"worker","Status":"started"},{"ID":"u3","ProcessName":"worker","Status":"started"}]}`).
+		on("GET /apps/myapp/env", http.StatusOK, `[{"name":"DEBUG","value":"1","public":true},{"name":"OLD","value":"x","public":true},{"name":"SECRET","value":"*** (private variable)","public":false}]`).
+		on("GET /services/instances?app=myapp", http.StatusOK, `[{"service":"redis","instances":["cache"]}]`).
+		on("POST /apps/myapp/plan", http.StatusOK, `{"Message":"plan changed\n"}`).
+		on("PUT /services/instances/mydb/myapp", http.StatusOK, `{"Message":"mydb bound\n"}`).
+		on("POST /apps/myapp/env", http.StatusOK, `{"Message":"variable(s) successfully exported\n"}`).
+		on("PUT /apps/myapp/units", http.StatusOK, `{"Message":"units added\n"}`).
+		on("POST /apps/myapp/cname", http.StatusOK, `{}`).
+		on("DELETE /apps/myapp/cname", http.StatusOK, `{}`).
+		on("DELETE /apps/myapp/env", http.StatusOK, `{"Message":"variable(s) successfully unset\n"}`).
+		on("DELETE /apps/myapp/units", http.StatusOK, `{"Message":"units removed\n"}`).
+		on("DELETE /services/instances/cache/myapp", http.StatusOK, `{"Message":"cache unbound\n"}`)
+}
+
+func (s *S) TestAppApplyInfo(c *check.C) {
+	c.Assert((&appApply{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppApply(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("y\n"),
+	}
+	fsystem = &fstest.RecordingFs{FileContent: appApplyFile}
+	defer func() {
+		fsystem = nil
+	}()
+	api := appApplyStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appApply{}
+	command.Flags().Parse(true, []string{"-f", "tsuru.yaml", "--prune"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/myapp",
+		"GET /apps/myapp/env",
+		"GET /services/instances?app=myapp",
+		"POST /apps/myapp/plan",
+		"PUT /services/instances/mydb/myapp",
+		"POST /apps/myapp/env",
+		"PUT /apps/myapp/units",
+		"POST /apps/myapp/cname",
+		"DELETE /apps/myapp/cname",
+		"DELETE /apps/myapp/env",
+		"DELETE /apps/myapp/units?process=worker&units=1",
+		"DELETE /services/instances/cache/myapp",
+	})
+	c.Assert(strings.TrimSpace(api.bodies[5]), check.Equals, `{"DEBUG":"0"}`)
+	c.Assert(api.bodies[6], check.Matches, `.*units=2.*`)
+	c.Assert(api.bodies[6], check.Matches, `.*process=web.*`)
+	c.Assert(api.bodies[7], check.Matches, `.*www.example.com.*`)
+	c.Assert(api.bodies[8], check.Matches, `.*old.example.com.*`)
+	expected := `Changes to app "myapp":
+  ~ plan: small -> medium
+  + bind service instance mydb
+  ~ set 1 variable(s): DEBUG
+  + units of process web: 1 -> 3
+  + cname(s): www.example.com
+  - cname(s): old.example.com
+  - unset 1 variable(s): OLD
+  - units of process worker: 2 -> 1
+  - unbind service instance cache
+  SECRET is private in the app and will be left unchanged
+Apply these changes to app "myapp"? (y/n) `
+	c.Assert(strings.HasPrefix(stdout.String(), expected), check.Equals, true, check.Commentf("%s", stdout.String()))
+	c.Assert(stdout.String(), check.Matches, `(?s).*==> ~ plan: small -> medium
+plan changed
+==> \+ bind service instance mydb
+mydb bound
+.*App "myapp" is up to date with "tsuru.yaml".
+`)
+}
+
+func (s *S) TestAppApplyKeepsVariablesWithoutPrune(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fsystem = &fstest.RecordingFs{FileContent: "name: myapp\nplan: small\nenv:\n  DEBUG: \"1\"\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	api := appApplyStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appApply{}
+	command.Flags().Parse(true, []string{"-f", "tsuru.yaml", "-y"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	for _, call := range api.calls() {
+		c.Assert(call, check.Not(check.Equals), "DELETE /apps/myapp/env")
+	}
+	c.Assert(stdout.String(), check.Equals, "App \"myapp\" is already up to date with \"tsuru.yaml\".\n")
+}
+
+func (s *S) TestAppApplyUpToDate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fsystem = &fstest.RecordingFs{FileContent: "name: myapp\nplan: small\nunits:\n  web: 1\n  worker: 2\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	api := appApplyStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appApply{}
+	command.Flags().Parse(true, []string{"-f", "tsuru.yaml"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "App \"myapp\" is already up to date with \"tsuru.yaml\".\n")
+}
+
+func (s *S) TestAppApplyCreatesApp(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fsystem = &fstest.RecordingFs{FileContent: "name: newapp\nplatform: python\nunits:\n  web: 2\nenv:\n  DEBUG: \"1\"\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	api := newStubAPI().
+		on("GET /apps/newapp", http.StatusNotFound, "App newapp not found.").
+		on("POST /apps", http.StatusOK, `{"status":"success","repository_url":""}`).
+		on("POST /apps/newapp/env", http.StatusOK, `{"Message":"variable(s) successfully exported\n"}`).
+		on("PUT /apps/newapp/units", http.StatusOK, `{"Message":"units added\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appApply{}
+	command.Flags().Parse(true, []string{"-f", "tsuru.yaml", "-y"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/newapp",
+		"POST /apps",
+		"POST /apps/newapp/env",
+		"PUT /apps/newapp/units",
+	})
+	c.Assert(api.bodies[1], check.Matches, `.*"name":"newapp".*"platform":"python".*`)
+	c.Assert(stdout.String(), check.Matches, `(?s)Changes to app "newapp":
+  \+ create app "newapp" \(platform: python\)
+  ~ set 1 variable\(s\): DEBUG
+  \+ units of process web: 0 -> 2
+.*`)
+}
+
+func (s *S) TestAppApplyMissingPlatform(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fsystem = &fstest.RecordingFs{FileContent: "name: newapp\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	api := newStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appApply{}
+	command.Flags().Parse(true, []string{"-f", "tsuru.yaml"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `app "newapp" doesn't exist, and the file doesn't define its platform`)
+}
+
+func (s *S) TestAppApplyPlatformChange(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fsystem = &fstest.RecordingFs{FileContent: "name: myapp\nplatform: ruby\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	client := cmd.NewClient(&http.Client{Transport: appApplyStubAPI()}, nil, manager)
+	command := appApply{}
+	command.Flags().Parse(true, []string{"-f", "tsuru.yaml"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `the platform of app "myapp" is "python", it can't be changed to "ruby"`)
+}
+
+func (s *S) TestAppApplyWithoutFile(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	command := appApply{}
+	command.Flags().Parse(true, []string{})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `Please use the -f/--file flag .*`)
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,12 +15,14 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
 
 	tsuruapp "github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/cmd"
+	tsuruErrors "github.com/tsuru/tsuru/errors"
 	"launchpad.net/gnuflag"
 )
 
@@ -153,6 +156,11 @@ func (c *appRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if !c.Confirm(context, fmt.Sprintf(`Are you sure you want to remove app "%s"?`, appName)) {
 		return nil
 	}
+	return removeApp(context.Stdout, client, appName)
+}
+
+// removeApp removes the app, streaming the output of the API to w.
+func removeApp(w io.Writer, client *cmd.Client, appName string) error {
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s", appName))
 	if err != nil {
 		return err
@@ -165,7 +173,7 @@ func (c *appRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	return cmd.StreamJSONResponse(context.Stdout, response)
+	return cmd.StreamJSONResponse(w, response)
 }
 
 func (c *appRemove) Flags() *gnuflag.FlagSet {
@@ -180,79 +188,124 @@ func (c *appRemove) Flags() *gnuflag.FlagSet {
 
 type appInfo struct {
 	cmd.GuessingCommand
+	fs           *gnuflag.FlagSet
+	format       string
+	template     string
+	watch        bool
+	interval     time.Duration
+	untilHealthy bool
 }
 
 func (c *appInfo) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-info",
-		Usage: "app-info [-a/--app appname]",
+		Usage: "app-info [-a/--app appname] [--format json|yaml] [--template template] [-w/--watch] [--interval 2s] [--until-healthy]",
 		Desc: `Shows information about a specific app. Its state, platform, git repository,
 etc. You need to be a member of a team that has access to the app to be able to
-see information about it.`,
+see information about it.
+
+The [[--format]] flag prints the whole app, including its units grouped by
+process, containers, service instances, plan and lock, as JSON or YAML, so it
+can be consumed by scripts.
+
+The [[--template]] flag formats the app using a Go template, for example:
+
+::
+
+    $ tsuru app-info -a myapp --template '{{.Address}}'
+    $ tsuru app-info -a myapp --template '{{range .Units.web}}{{.ID}}{{"\n"}}{{end}}'
+
+The template receives the same data used by [[--format]].
+
+The [[--watch]] flag refreshes the output every [[--interval]] (2 seconds by
+default), listing the units whose state changed below the app and how long ago
+they changed. Press Ctrl-C to stop watching. The [[--until-healthy]] flag keeps
+watching until all units of the app are started, and then exits successfully.`,
 		MinArgs: 0,
 	}
 }
 
-func (c *appInfo) Run(context *cmd.Context, client *cmd.Client) error {
-	appName, err := c.Guess()
-	if err != nil {
-		return err
-	}
-	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s", appName))
-	if err != nil {
-		return err
+func (c *appInfo) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.StringVar(&c.format, "format", "", "Output format: json or yaml")
+		c.fs.StringVar(&c.template, "template", "", "Format the output using the given Go template")
+		c.fs.BoolVar(&c.watch, "watch", false, "Refresh the output periodically, until Ctrl-C is pressed")
+		c.fs.BoolVar(&c.watch, "w", false, "Refresh the output periodically, until Ctrl-C is pressed")
+		c.fs.DurationVar(&c.interval, "interval", defaultWatchInterval, "Interval between refreshes when watching")
+		c.fs.BoolVar(&c.untilHealthy, "until-healthy", false, "Watch until all units are started")
 	}
-	request, err := http.NewRequest("GET", url, nil)
-	if err != nil {
+	return c.fs
+}
+
+func (c *appInfo) Run(context *cmd.Context, client *cmd.Client) error {
+	if err := checkFormat(c.format, "json", "yaml"); err != nil {
 		return err
 	}
-	response, err := client.Do(request)
-	if err != nil {
-		return err
+	if c.format != "" && c.template != "" {
+		return errors.New("the --format and --template flags can't be used together")
 	}
-	if response.StatusCode == http.StatusNoContent {
-		return nil
+	var tmpl *template.Template
+	if c.template != "" {
+		var err error
+		tmpl, err = template.New("app-info").Parse(c.template)
+		if err != nil {
+			return fmt.Errorf("invalid template: %s", err)
+		}
 	}
-	defer response.Body.Close()
-	result, err := ioutil.ReadAll(response.Body)
+	appName, err := c.Guess()
 	if err != nil {
 		return err
 	}
-	url, err = cmd.GetURL(fmt.Sprintf("/docker/node/apps/%s/containers", appName))
-	if err != nil {
+	if !c.watch && !c.untilHealthy {
+		_, err = c.load(appName, tmpl, client, context)
 		return err
 	}
-	request, err = http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-	response, err = client.Do(request)
-	var adminResult []byte
-	if err == nil {
-		defer response.Body.Close()
-		adminResult, err = ioutil.ReadAll(response.Body)
-		if err != nil {
-			return err
+	watcher := newUnitWatcher()
+	return runWatch(context, c.interval, c.untilHealthy, func(w io.Writer) (bool, error) {
+		a, err := c.load(appName, tmpl, client, &cmd.Context{Stdout: w, Stderr: w})
+		if err != nil || a == nil {
+			return false, err
 		}
+		watcher.update(a.Units)
+		if c.format == "" && tmpl == nil {
+			watcher.render(w)
+		}
+		return unitsHealthy(a.Units), nil
+	})
+}
+
+// load fetches the app and displays it, returning the loaded app.
+func (c *appInfo) load(appName string, tmpl *template.Template, client *cmd.Client, context *cmd.Context) (*app, error) {
+	sections := fetchAppInfoSections(client, []string{
+		fmt.Sprintf("/apps/%s", appName),
+		fmt.Sprintf("/docker/node/apps/%s/containers", appName),
+		fmt.Sprintf("/services/instances?app=%s", appName),
+	})
+	if sections[0].err == errAppInfoTimeout {
+		return nil, fmt.Errorf("timed out after %s while loading app %q", appInfoTimeout, appName)
+	} else if sections[0].err != nil {
+		return nil, sections[0].err
 	}
-	url, err = cmd.GetURL(fmt.Sprintf("/services/instances?app=%s", appName))
-	if err != nil {
-		return err
+	if sections[0].noContent {
+		return nil, nil
 	}
-	request, err = http.NewRequest("GET", url, nil)
+	var a app
+	err := json.Unmarshal(sections[0].body, &a)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	response, err = client.Do(request)
-	var servicesResult []byte
-	if err == nil {
-		defer response.Body.Close()
-		servicesResult, err = ioutil.ReadAll(response.Body)
-		if err != nil {
-			return err
+	json.Unmarshal(sections[1].body, &a.containers)
+	json.Unmarshal(sections[2].body, &a.services)
+	for i, name := range []string{"containers", "services"} {
+		if err := sections[i+1].err; err != nil {
+			fmt.Fprintf(context.Stderr, "Warning: %s: %s\n", name, appInfoWarning(err))
 		}
 	}
-	return c.Show(result, adminResult, servicesResult, context)
+	if c.format != "" || tmpl != nil {
+		return &a, c.ShowDocument(&a, tmpl, context)
+	}
+	return &a, c.Show(&a, context)
 }
 
 type unit struct {
@@ -317,6 +370,55 @@ type container struct {
 	LastStatusUpdate time.Time
 }
 
+// getApp loads the app with the given name from the API.
+func getApp(client *cmd.Client, appName string) (*app, error) {
+	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s", appName))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	var a app
+	err = json.NewDecoder(response.Body).Decode(&a)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// getServiceInstances returns the service instances bound to the app.
+func getServiceInstances(client *cmd.Client, appName string) ([]serviceData, error) {
+	url, err := cmd.GetURL(fmt.Sprintf("/services/instances?app=%s", appName))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	var services []serviceData
+	err = json.NewDecoder(response.Body).Decode(&services)
+	if err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
 func (a *app) Addr() string {
 	cnames := strings.Join(a.CName, ", ")
 	if cnames != "" {
@@ -325,6 +427,35 @@ func (a *app) Addr() string {
 	return a.Ip
 }
 
+func (a *app) addrs() []string {
+	return append(a.cnames(), a.Ip)
+}
+
+// cnames returns the cnames of the app, skipping empty ones.
+func (a *app) cnames() []string {
+	cnames := make([]string, 0, len(a.CName))
+	for _, cname := range a.CName {
+		if cname != "" {
+			cnames = append(cnames, cname)
+		}
+	}
+	return cnames
+}
+
+// unitsSummary returns the number of units of the app that are available and
+// the total number of units.
+func (a *app) unitsSummary() (available int, total int) {
+	for _, unit := range a.Units {
+		if unit.ID != "" {
+			total++
+			if unit.Available() {
+				available++
+			}
+		}
+	}
+	return available, total
+}
+
 func (a *app) GetTeams() string {
 	return strings.Join(a.Teams, ", ")
 }
@@ -420,18 +551,233 @@ Pool: {{.Pool}}{{if .Lock.Locked}}
 	return tplBuffer.String() + buf.String()
 }
 
-func (c *appInfo) Show(result []byte, adminResult []byte, servicesResult []byte, context *cmd.Context) error {
-	var a app
-	err := json.Unmarshal(result, &a)
+func (c *appInfo) Show(a *app, context *cmd.Context) error {
+	fmt.Fprintln(context.Stdout, a)
+	return nil
+}
+
+// appInfoTimeout is how long app-info waits for the API. It's shared by all
+// the requests made by the command, which run in parallel.
+var appInfoTimeout = 30 * time.Second
+
+var errAppInfoTimeout = errors.New("timed out")
+
+type appInfoSection struct {
+	body      []byte
+	noContent bool
+	err       error
+}
+
+// fetchAppInfoSections requests the given paths in parallel, returning the
+// sections in the same order as the paths. Requests that don't finish within
+// appInfoTimeout fail with errAppInfoTimeout.
+func fetchAppInfoSections(client *cmd.Client, paths []string) []appInfoSection {
+	channels := make([]chan appInfoSection, len(paths))
+	for i, path := range paths {
+		channels[i] = make(chan appInfoSection, 1)
+		go func(path string, ch chan<- appInfoSection) {
+			ch <- fetchAppInfoSection(client, path)
+		}(path, channels[i])
+	}
+	sections := make([]appInfoSection, len(paths))
+	timer := time.NewTimer(appInfoTimeout)
+	defer timer.Stop()
+	var timedOut bool
+	for i, ch := range channels {
+		if timedOut {
+			select {
+			case sections[i] = <-ch:
+			default:
+				sections[i].err = errAppInfoTimeout
+			}
+			continue
+		}
+		select {
+		case sections[i] = <-ch:
+		case <-timer.C:
+			timedOut = true
+			sections[i].err = errAppInfoTimeout
+		}
+	}
+	return sections
+}
+
+func fetchAppInfoSection(client *cmd.Client, path string) appInfoSection {
+	url, err := cmd.GetURL(path)
 	if err != nil {
-		return err
+		return appInfoSection{err: err}
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return appInfoSection{err: err}
 	}
-	json.Unmarshal(adminResult, &a.containers)
-	json.Unmarshal(servicesResult, &a.services)
-	fmt.Fprintln(context.Stdout, &a)
+	response, err := client.Do(request)
+	if err != nil {
+		return appInfoSection{err: err}
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNoContent {
+		return appInfoSection{noContent: true}
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	return appInfoSection{body: body, err: err}
+}
+
+// appInfoWarning describes why an optional section of app-info couldn't be
+// loaded.
+func appInfoWarning(err error) string {
+	if e, ok := err.(*tsuruErrors.HTTP); ok {
+		switch e.Code {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return "permission denied"
+		case http.StatusNotFound:
+			return "not found"
+		}
+		return strings.TrimSpace(e.Message)
+	}
+	if err == errAppInfoTimeout {
+		return fmt.Sprintf("timed out after %s", appInfoTimeout)
+	}
+	return err.Error()
+}
+
+// ShowDocument prints the app using the structured format or the template
+// given by the user.
+func (c *appInfo) ShowDocument(a *app, tmpl *template.Template, context *cmd.Context) error {
+	doc := newAppDocument(a)
+	if tmpl == nil {
+		return renderStructured(context.Stdout, c.format, doc)
+	}
+	err := tmpl.Execute(context.Stdout, doc)
+	if err != nil {
+		return fmt.Errorf("unable to execute the template: %s", err)
+	}
+	fmt.Fprintln(context.Stdout)
 	return nil
 }
 
+// appDocument is the representation of an app used by the json and yaml
+// formats and by the templates of app-info. Dates are formatted as RFC 3339
+// strings, so they look the same in every format.
+type appDocument struct {
+	Name       string               `json:"name" yaml:"name"`
+	Platform   string               `json:"platform" yaml:"platform"`
+	Repository string               `json:"repository" yaml:"repository"`
+	Teams      []string             `json:"teams" yaml:"teams"`
+	Owner      string               `json:"owner" yaml:"owner"`
+	TeamOwner  string               `json:"teamowner" yaml:"teamowner"`
+	Pool       string               `json:"pool" yaml:"pool"`
+	Address    string               `json:"address" yaml:"address"`
+	Ip         string               `json:"ip" yaml:"ip"`
+	CName      []string             `json:"cname" yaml:"cname"`
+	Deploys    uint                 `json:"deploys" yaml:"deploys"`
+	Units      map[string][]unitDoc `json:"units" yaml:"units"`
+	Containers []containerDoc       `json:"containers" yaml:"containers"`
+	Services   []serviceDataDoc     `json:"services" yaml:"services"`
+	Plan       tsuruapp.Plan        `json:"plan" yaml:"plan"`
+	Lock       lockDoc              `json:"lock" yaml:"lock"`
+}
+
+type unitDoc struct {
+	ID          string `json:"id" yaml:"id"`
+	Ip          string `json:"ip" yaml:"ip"`
+	Status      string `json:"status" yaml:"status"`
+	ProcessName string `json:"processname" yaml:"processname"`
+}
+
+type containerDoc struct {
+	ID               string `json:"id" yaml:"id"`
+	Type             string `json:"type" yaml:"type"`
+	IP               string `json:"ip" yaml:"ip"`
+	HostAddr         string `json:"hostaddr" yaml:"hostaddr"`
+	HostPort         string `json:"hostport" yaml:"hostport"`
+	SSHHostPort      string `json:"sshhostport" yaml:"sshhostport"`
+	Status           string `json:"status" yaml:"status"`
+	Version          string `json:"version" yaml:"version"`
+	Image            string `json:"image" yaml:"image"`
+	LastStatusUpdate string `json:"laststatusupdate" yaml:"laststatusupdate"`
+}
+
+type serviceDataDoc struct {
+	Service   string   `json:"service" yaml:"service"`
+	Instances []string `json:"instances" yaml:"instances"`
+}
+
+type lockDoc struct {
+	Locked      bool   `json:"locked" yaml:"locked"`
+	Reason      string `json:"reason" yaml:"reason"`
+	Owner       string `json:"owner" yaml:"owner"`
+	AcquireDate string `json:"acquiredate" yaml:"acquiredate"`
+}
+
+func newAppDocument(a *app) *appDocument {
+	doc := appDocument{
+		Name:       a.Name,
+		Platform:   a.Platform,
+		Repository: a.Repository,
+		Teams:      a.Teams,
+		Owner:      a.Owner,
+		TeamOwner:  a.TeamOwner,
+		Pool:       a.Pool,
+		Address:    a.Addr(),
+		Ip:         a.Ip,
+		CName:      a.CName,
+		Deploys:    a.Deploys,
+		Units:      make(map[string][]unitDoc),
+		Containers: []containerDoc{},
+		Services:   []serviceDataDoc{},
+		Plan:       a.Plan,
+		Lock: lockDoc{
+			Locked:      a.Lock.Locked,
+			Reason:      a.Lock.Reason,
+			Owner:       a.Lock.Owner,
+			AcquireDate: formatDocumentTime(a.Lock.AcquireDate),
+		},
+	}
+	for _, u := range a.Units {
+		if u.ID == "" {
+			continue
+		}
+		doc.Units[u.ProcessName] = append(doc.Units[u.ProcessName], unitDoc{
+			ID:          u.ID,
+			Ip:          u.Ip,
+			Status:      u.Status,
+			ProcessName: u.ProcessName,
+		})
+	}
+	for _, cont := range a.containers {
+		doc.Containers = append(doc.Containers, containerDoc{
+			ID:               cont.ID,
+			Type:             cont.Type,
+			IP:               cont.IP,
+			HostAddr:         cont.HostAddr,
+			HostPort:         cont.HostPort,
+			SSHHostPort:      cont.SSHHostPort,
+			Status:           cont.Status,
+			Version:          cont.Version,
+			Image:            cont.Image,
+			LastStatusUpdate: formatDocumentTime(cont.LastStatusUpdate),
+		})
+	}
+	for _, service := range a.services {
+		if len(service.Instances) == 0 {
+			continue
+		}
+		doc.Services = append(doc.Services, serviceDataDoc{
+			Service:   service.Service,
+			Instances: service.Instances,
+		})
+	}
+	return &doc
+}
+
+func formatDocumentTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
 type appGrant struct {
 	cmd.GuessingCommand
 }
@@ -545,74 +891,193 @@ func (f *appFilter) queryString(client *cmd.Client) (url.Values, error) {
 }
 
 type appList struct {
-	fs         *gnuflag.FlagSet
-	filter     appFilter
-	simplified bool
+	fs           *gnuflag.FlagSet
+	filter       appFilter
+	simplified   bool
+	columns      string
+	sort         string
+	reverse      bool
+	format       string
+	pool         string
+	unhealthy    bool
+	watch        bool
+	interval     time.Duration
+	untilHealthy bool
 }
 
 func (c *appList) Run(context *cmd.Context, client *cmd.Client) error {
+	if err := checkFormat(c.format, "table", "json", "csv"); err != nil {
+		return err
+	}
+	if c.watch || c.untilHealthy {
+		return c.runWatch(context, client)
+	}
+	result, err := c.fetch(client)
+	if err != nil || result == nil {
+		return err
+	}
+	return c.Show(result, context)
+}
+
+func (c *appList) runWatch(context *cmd.Context, client *cmd.Client) error {
+	watcher := newUnitWatcher()
+	return runWatch(context, c.interval, c.untilHealthy, func(w io.Writer) (bool, error) {
+		result, err := c.fetch(client)
+		if err != nil {
+			return false, err
+		}
+		var apps []app
+		if result != nil {
+			apps, err = c.parse(result)
+			if err != nil {
+				return false, err
+			}
+		}
+		var units []unit
+		for _, a := range apps {
+			units = append(units, a.Units...)
+		}
+		err = c.show(apps, &cmd.Context{Stdout: w, Stderr: w})
+		if err != nil {
+			return false, err
+		}
+		watcher.update(units)
+		if c.format == "" && !c.simplified {
+			watcher.render(w)
+		}
+		return unitsHealthy(units), nil
+	})
+}
+
+// fetch returns the list of apps from the API, or nil when there are no apps.
+func (c *appList) fetch(client *cmd.Client) ([]byte, error) {
 	qs, err := c.filter.queryString(client)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	url, err := cmd.GetURL(fmt.Sprintf("/apps?%s", qs.Encode()))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	request, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	response, err := client.Do(request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if response.StatusCode == http.StatusNoContent {
-		return nil
+		return nil, nil
 	}
 	defer response.Body.Close()
-	result, err := ioutil.ReadAll(response.Body)
+	return ioutil.ReadAll(response.Body)
+}
+
+func (c *appList) Show(result []byte, context *cmd.Context) error {
+	apps, err := c.parse(result)
 	if err != nil {
 		return err
 	}
-	return c.Show(result, context)
+	return c.show(apps, context)
 }
 
-func (c *appList) Show(result []byte, context *cmd.Context) error {
+// parse decodes the list of apps, applying the client side filters and the
+// sort order.
+func (c *appList) parse(result []byte) ([]app, error) {
+	sortColumn := appListColumns["name"]
+	if c.sort != "" {
+		var ok bool
+		sortColumn, ok = appListColumns[strings.ToLower(c.sort)]
+		if !ok {
+			return nil, fmt.Errorf("invalid sort column %q, valid columns are: %s", c.sort, strings.Join(appListColumnNames, ", "))
+		}
+	}
 	var apps []app
 	err := json.Unmarshal(result, &apps)
+	if err != nil {
+		return nil, err
+	}
+	apps = c.filterApps(apps)
+	sort.Stable(appsByColumn{apps: apps, column: appListColumns["name"]})
+	if c.sort != "" || c.reverse {
+		sort.Stable(appsByColumn{apps: apps, column: sortColumn, reverse: c.reverse})
+	}
+	return apps, nil
+}
+
+func (c *appList) show(apps []app, context *cmd.Context) error {
+	columns, err := parseAppListColumns(c.columns)
 	if err != nil {
 		return err
 	}
-	table := cmd.NewTable()
 	if c.simplified {
 		for _, app := range apps {
 			fmt.Fprintln(context.Stdout, app.Name)
 		}
 		return nil
 	}
-	table.Headers = cmd.Row([]string{"Application", "Units State Summary", "Address"})
-	for _, app := range apps {
-		var available int
-		var total int
-		for _, unit := range app.Units {
-			if unit.ID != "" {
-				total++
-				if unit.Available() {
-					available++
-				}
+	switch c.format {
+	case "json":
+		data := make([]map[string]interface{}, len(apps))
+		for i := range apps {
+			data[i] = make(map[string]interface{}, len(columns))
+			for _, name := range columns {
+				data[i][name] = appListColumns[name].value(&apps[i])
+			}
+		}
+		return renderStructured(context.Stdout, c.format, data)
+	case "csv":
+		w := csv.NewWriter(context.Stdout)
+		w.Write(columns)
+		for i := range apps {
+			record := make([]string, len(columns))
+			for j, name := range columns {
+				record[j] = strings.Replace(appListColumns[name].text(&apps[i]), "\n", ", ", -1)
 			}
+			w.Write(record)
 		}
-		summary := fmt.Sprintf("%d of %d units in-service", available, total)
-		addrs := strings.Replace(app.Addr(), ", ", "\n", -1)
-		table.AddRow(cmd.Row([]string{app.Name, summary, addrs}))
+		w.Flush()
+		return w.Error()
+	}
+	table := cmd.NewTable()
+	headers := make([]string, len(columns))
+	for i, name := range columns {
+		headers[i] = appListColumns[name].header
+	}
+	table.Headers = cmd.Row(headers)
+	for i := range apps {
+		row := make([]string, len(columns))
+		for j, name := range columns {
+			row[j] = appListColumns[name].text(&apps[i])
+		}
+		table.AddRow(cmd.Row(row))
 	}
 	table.LineSeparator = true
-	table.Sort()
 	context.Stdout.Write(table.Bytes())
 	return nil
 }
 
+// filterApps applies the filters that are not supported by the API.
+func (c *appList) filterApps(apps []app) []app {
+	if c.pool == "" && !c.unhealthy {
+		return apps
+	}
+	filtered := make([]app, 0, len(apps))
+	for _, a := range apps {
+		if c.pool != "" && a.Pool != c.pool {
+			continue
+		}
+		if c.unhealthy {
+			if available, total := a.unitsSummary(); available == total {
+				continue
+			}
+		}
+		filtered = append(filtered, a)
+	}
+	return filtered
+}
+
 func (c *appList) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
 		c.fs = gnuflag.NewFlagSet("app-list", gnuflag.ExitOnError)
@@ -627,6 +1092,16 @@ func (c *appList) Flags() *gnuflag.FlagSet {
 		c.fs.BoolVar(&c.filter.locked, "locked", false, "Display only applications that are locked")
 		c.fs.BoolVar(&c.filter.locked, "l", false, "Display only applications that are locked")
 		c.fs.BoolVar(&c.simplified, "q", false, "Display only applications name")
+		c.fs.StringVar(&c.pool, "pool", "", "Display only applications in the given pool")
+		c.fs.BoolVar(&c.unhealthy, "unhealthy", false, "Display only applications with units that are not started")
+		c.fs.StringVar(&c.columns, "columns", "", "Comma-separated list of columns to display")
+		c.fs.StringVar(&c.sort, "sort", "", "Sort applications by the given column")
+		c.fs.BoolVar(&c.reverse, "reverse", false, "Reverse the sort order")
+		c.fs.StringVar(&c.format, "format", "", "Output format: table, json or csv")
+		c.fs.BoolVar(&c.watch, "watch", false, "Refresh the list periodically, until Ctrl-C is pressed")
+		c.fs.BoolVar(&c.watch, "w", false, "Refresh the list periodically, until Ctrl-C is pressed")
+		c.fs.DurationVar(&c.interval, "interval", defaultWatchInterval, "Interval between refreshes when watching")
+		c.fs.BoolVar(&c.untilHealthy, "until-healthy", false, "Watch until all units of the listed applications are started")
 	}
 	return c.fs
 }
@@ -634,16 +1109,134 @@ func (c *appList) Flags() *gnuflag.FlagSet {
 func (c *appList) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-list",
-		Usage: "app-list",
+		Usage: "app-list [-n/--name name] [-p/--platform platform] [-t/--team team] [-u/--user user] [-l/--locked] [--pool pool] [--unhealthy] [--columns col1,col2,...] [--sort column [--reverse]] [--format table|json|csv] [-w/--watch] [--interval 2s] [--until-healthy] [-q]",
 		Desc: `Lists all apps that you have access to. App access is controlled by teams. If
 your team has access to an app, then you have access to it.
 
-Flags can be used to filter the list of applications.`,
+Flags can be used to filter the list of applications. The [[--pool]] flag
+displays only applications in the given pool, and [[--unhealthy]] displays
+only applications that have at least one unit that is not started.
+
+The [[--columns]] flag chooses which columns are displayed, and in which order.
+The available columns are: name, platform, pool, plan, teamowner, units,
+address and locked. The default is "name,units,address".
+
+Applications are sorted by name, unless the [[--sort]] flag is used to sort
+them by another column. The [[--reverse]] flag reverses the order.
+
+The [[--format]] flag changes the output format to json or csv.
+
+The [[--watch]] flag refreshes the list every [[--interval]] (2 seconds by
+default), listing the units whose state changed below the table and how long
+ago they changed. Press Ctrl-C to stop watching. The [[--until-healthy]] flag keeps
+watching until all units of the listed applications are started, and then
+exits successfully.`,
+	}
+}
+
+// appListColumn describes a column that can be displayed by app-list. text is
+// the value used in tables and CSV files, value is the one used in JSON and
+// key is used for sorting.
+type appListColumn struct {
+	header string
+	text   func(a *app) string
+	value  func(a *app) interface{}
+	key    func(a *app) string
+}
+
+var appListColumnNames = []string{"name", "platform", "pool", "plan", "teamowner", "units", "address", "locked"}
+
+var appListDefaultColumns = []string{"name", "units", "address"}
+
+var appListColumns = map[string]appListColumn{
+	"name":      stringAppListColumn("Application", func(a *app) string { return a.Name }),
+	"platform":  stringAppListColumn("Platform", func(a *app) string { return a.Platform }),
+	"pool":      stringAppListColumn("Pool", func(a *app) string { return a.Pool }),
+	"plan":      stringAppListColumn("Plan", func(a *app) string { return a.Plan.Name }),
+	"teamowner": stringAppListColumn("Team Owner", func(a *app) string { return a.TeamOwner }),
+	"units": {
+		header: "Units State Summary",
+		text: func(a *app) string {
+			available, total := a.unitsSummary()
+			return fmt.Sprintf("%d of %d units in-service", available, total)
+		},
+		value: func(a *app) interface{} {
+			available, total := a.unitsSummary()
+			return map[string]int{"available": available, "total": total}
+		},
+		key: func(a *app) string {
+			available, total := a.unitsSummary()
+			return fmt.Sprintf("%010d %010d", available, total)
+		},
+	},
+	"address": {
+		header: "Address",
+		text:   func(a *app) string { return strings.Replace(a.Addr(), ", ", "\n", -1) },
+		value:  func(a *app) interface{} { return a.addrs() },
+		key:    func(a *app) string { return a.Addr() },
+	},
+	"locked": {
+		header: "Locked",
+		text:   func(a *app) string { return strconv.FormatBool(a.Lock.Locked) },
+		value:  func(a *app) interface{} { return a.Lock.Locked },
+		key:    func(a *app) string { return strconv.FormatBool(a.Lock.Locked) },
+	},
+}
+
+func stringAppListColumn(header string, text func(a *app) string) appListColumn {
+	return appListColumn{
+		header: header,
+		text:   text,
+		value:  func(a *app) interface{} { return text(a) },
+		key:    text,
+	}
+}
+
+func parseAppListColumns(value string) ([]string, error) {
+	if value == "" {
+		return appListDefaultColumns, nil
+	}
+	var columns []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := appListColumns[name]; !ok {
+			return nil, fmt.Errorf("invalid column %q, valid columns are: %s", name, strings.Join(appListColumnNames, ", "))
+		}
+		columns = append(columns, name)
+	}
+	if len(columns) == 0 {
+		return appListDefaultColumns, nil
+	}
+	return columns, nil
+}
+
+type appsByColumn struct {
+	apps    []app
+	column  appListColumn
+	reverse bool
+}
+
+func (l appsByColumn) Len() int {
+	return len(l.apps)
+}
+
+func (l appsByColumn) Less(i, j int) bool {
+	if l.reverse {
+		i, j = j, i
 	}
+	return l.column.key(&l.apps[i]) < l.column.key(&l.apps[j])
+}
+
+func (l appsByColumn) Swap(i, j int) {
+	l.apps[i], l.apps[j] = l.apps[j], l.apps[i]
 }
 
 type appStop struct {
 	cmd.GuessingCommand
+	lockWaiter
 	process string
 	fs      *gnuflag.FlagSet
 }
@@ -651,7 +1244,7 @@ type appStop struct {
 func (c *appStop) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:    "app-stop",
-		Usage:   "app-stop [-a/--app appname] [-p/--process processname]",
+		Usage:   "app-stop [-a/--app appname] [-p/--process processname] [--wait-lock 5m]",
 		Desc:    "Stops an application, or one of the processes of the application.",
 		MinArgs: 0,
 	}
@@ -663,6 +1256,10 @@ func (c *appStop) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/stop?process=%s", appName, c.process))
 	if err != nil {
 		return err
@@ -685,7 +1282,7 @@ func (c *appStop) Run(context *cmd.Context, client *cmd.Client) error {
 
 func (c *appStop) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
-		c.fs = c.GuessingCommand.Flags()
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
 		c.fs.StringVar(&c.process, "process", "", "Process name")
 		c.fs.StringVar(&c.process, "p", "", "Process name")
 	}
@@ -694,6 +1291,7 @@ func (c *appStop) Flags() *gnuflag.FlagSet {
 
 type appStart struct {
 	cmd.GuessingCommand
+	lockWaiter
 	process string
 	fs      *gnuflag.FlagSet
 }
@@ -701,7 +1299,7 @@ type appStart struct {
 func (c *appStart) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:    "app-start",
-		Usage:   "app-start [-a/--app appname] [-p/--process processname]",
+		Usage:   "app-start [-a/--app appname] [-p/--process processname] [--wait-lock 5m]",
 		Desc:    "Starts an application, or one of the processes of the application.",
 		MinArgs: 0,
 	}
@@ -713,6 +1311,10 @@ func (c *appStart) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/start?process=%s", appName, c.process))
 	if err != nil {
 		return err
@@ -735,7 +1337,7 @@ func (c *appStart) Run(context *cmd.Context, client *cmd.Client) error {
 
 func (c *appStart) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
-		c.fs = c.GuessingCommand.Flags()
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
 		c.fs.StringVar(&c.process, "process", "", "Process name")
 		c.fs.StringVar(&c.process, "p", "", "Process name")
 	}
@@ -744,7 +1346,12 @@ func (c *appStart) Flags() *gnuflag.FlagSet {
 
 type appRestart struct {
 	cmd.GuessingCommand
+	lockWaiter
 	process string
+	rolling bool
+	batch   int
+	pause   time.Duration
+	timeout time.Duration
 	fs      *gnuflag.FlagSet
 }
 
@@ -754,7 +1361,23 @@ func (c *appRestart) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/restart?process=%s", appName, c.process))
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
+	if c.rolling {
+		if c.process != "" {
+			return errors.New("The --process and --rolling flags can't be used together.")
+		}
+		return c.rollingRestart(context, client, appName)
+	}
+	return restartApp(context.Stdout, client, appName, c.process)
+}
+
+// restartApp restarts the given process of the app, or all its processes if
+// process is empty, streaming the output of the API to w.
+func restartApp(w io.Writer, client *cmd.Client, appName, process string) error {
+	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/restart?process=%s", appName, process))
 	if err != nil {
 		return err
 	}
@@ -766,32 +1389,64 @@ func (c *appRestart) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	return cmd.StreamJSONResponse(context.Stdout, response)
+	return cmd.StreamJSONResponse(w, response)
 }
 
 func (c *appRestart) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "app-restart",
-		Usage:   "app-restart [-a/--app appname] [-p/--process processname]",
-		Desc:    `Restarts an application, or one of the processes of the application.`,
+		Name:  "app-restart",
+		Usage: "app-restart [-a/--app appname] [-p/--process processname] [--rolling [--batch 1] [--pause 0s] [--timeout 5m]] [--wait-lock 5m]",
+		Desc: `Restarts an application, or one of the processes of the application.
+
+With the [[--rolling]] flag, processes are restarted one batch at a time
+instead of all at once. Each batch has [[--batch]] processes (one by default),
+and every unit of a process is restarted together. After restarting a batch,
+the command waits until all of its units are started again, up to
+[[--timeout]], and then waits [[--pause]] before moving on to the next batch.
+If a batch fails, the rolling restart stops and reports which processes were
+restarted and which were not. Apps with units that don't belong to a named
+process can't be restarted with [[--rolling]].`,
 		MinArgs: 0,
 	}
 }
 
 func (c *appRestart) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
-		c.fs = c.GuessingCommand.Flags()
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
 		c.fs.StringVar(&c.process, "process", "", "Process name")
 		c.fs.StringVar(&c.process, "p", "", "Process name")
+		c.fs.BoolVar(&c.rolling, "rolling", false, "Restart the processes of the app in batches")
+		c.fs.IntVar(&c.batch, "batch", 1, "Number of processes restarted at a time in a rolling restart")
+		c.fs.DurationVar(&c.pause, "pause", 0, "Time to wait between the batches of a rolling restart")
+		c.fs.DurationVar(&c.timeout, "timeout", defaultRollingTimeout, "Maximum time to wait for the units of a batch to start")
 	}
 	return c.fs
 }
 
 type cnameAdd struct {
 	cmd.GuessingCommand
+	fs     *gnuflag.FlagSet
+	verify bool
+	force  bool
 }
 
 func (c *cnameAdd) Run(context *cmd.Context, client *cmd.Client) error {
+	if c.verify {
+		appName, err := c.Guess()
+		if err != nil {
+			return err
+		}
+		a, err := getApp(client, appName)
+		if err != nil {
+			return err
+		}
+		if !verifyCNames(context.Stdout, a, context.Args) {
+			if !c.force {
+				return errors.New("some cnames don't point to the app, create the DNS records above or use --force")
+			}
+			fmt.Fprintln(context.Stdout, "Warning: adding the cnames anyway, as requested with --force.")
+		}
+	}
 	err := addCName(context.Args, c.GuessingCommand, client)
 	if err != nil {
 		return err
@@ -803,16 +1458,32 @@ func (c *cnameAdd) Run(context *cmd.Context, client *cmd.Client) error {
 func (c *cnameAdd) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "cname-add",
-		Usage: "cname-add <cname> [<cname> ...] [-a/--app appname]",
+		Usage: "cname-add <cname> [<cname> ...] [-a/--app appname] [--verify [-f/--force]]",
 		Desc: `Adds a new CNAME to the application.
 
 It will not manage any DNS register, it's up to the user to create the DNS
 register. Once the app contains a custom CNAME, it will be displayed by "app-
-list" and "app-info".`,
+list" and "app-info".
+
+The [[--verify]] flag checks that each cname resolves to the address of the
+app before adding it. When a cname doesn't, the command prints the DNS record
+that must be created and doesn't add any cname, unless the [[--force]] flag is
+also used.`,
 		MinArgs: 1,
 	}
 }
 
+func (c *cnameAdd) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.BoolVar(&c.verify, "verify", false, "Check that the cnames point to the app before adding them")
+		forceMessage := "Add the cnames even when they don't point to the app"
+		c.fs.BoolVar(&c.force, "force", false, forceMessage)
+		c.fs.BoolVar(&c.force, "f", false, forceMessage)
+	}
+	return c.fs
+}
+
 type cnameRemove struct {
 	cmd.GuessingCommand
 }
@@ -844,26 +1515,7 @@ func unsetCName(v []string, g cmd.GuessingCommand, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/cname", appName))
-	if err != nil {
-		return err
-	}
-	cnames := make(map[string][]string)
-	cnames["cname"] = v
-	c, err := json.Marshal(cnames)
-	if err != nil {
-		return err
-	}
-	body := bytes.NewReader(c)
-	request, err := http.NewRequest("DELETE", url, body)
-	if err != nil {
-		return err
-	}
-	_, err = client.Do(request)
-	if err != nil {
-		return err
-	}
-	return nil
+	return requestCName("DELETE", appName, v, client)
 }
 
 func addCName(v []string, g cmd.GuessingCommand, client *cmd.Client) error {
@@ -871,6 +1523,10 @@ func addCName(v []string, g cmd.GuessingCommand, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	return requestCName("POST", appName, v, client)
+}
+
+func requestCName(method, appName string, v []string, client *cmd.Client) error {
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/cname", appName))
 	if err != nil {
 		return err
@@ -882,7 +1538,7 @@ func addCName(v []string, g cmd.GuessingCommand, client *cmd.Client) error {
 		return err
 	}
 	body := bytes.NewReader(c)
-	request, err := http.NewRequest("POST", url, body)
+	request, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return err
 	}
@@ -902,21 +1558,26 @@ func (c *TeamOwnerSet) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/team-owner", appName))
+	err = setTeamOwner(client, appName, context.Args[0])
 	if err != nil {
 		return err
 	}
-	body := strings.NewReader(context.Args[0])
-	request, err := http.NewRequest("POST", url, body)
+	fmt.Fprintln(context.Stdout, "app's owner team successfully changed.")
+	return nil
+}
+
+func setTeamOwner(client *cmd.Client, appName, teamOwner string) error {
+	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/team-owner", appName))
 	if err != nil {
 		return err
 	}
-	_, err = client.Do(request)
+	body := strings.NewReader(teamOwner)
+	request, err := http.NewRequest("POST", url, body)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(context.Stdout, "app's owner team successfully changed.")
-	return nil
+	_, err = client.Do(request)
+	return err
 }
 
 func (c *TeamOwnerSet) Info() *cmd.Info {
@@ -930,6 +1591,7 @@ func (c *TeamOwnerSet) Info() *cmd.Info {
 
 type unitAdd struct {
 	cmd.GuessingCommand
+	lockWaiter
 	fs      *gnuflag.FlagSet
 	process string
 }
@@ -937,7 +1599,7 @@ type unitAdd struct {
 func (c *unitAdd) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "unit-add",
-		Usage: "unit-add <# of units> [-a/--app appname] [-p/--process processname]",
+		Usage: "unit-add <# of units> [-a/--app appname] [-p/--process processname] [--wait-lock 5m]",
 		Desc: `Adds new units to a process of an application. You need to have access to the
 app to be able to add new units to it.`,
 		MinArgs: 1,
@@ -946,7 +1608,7 @@ app to be able to add new units to it.`,
 
 func (c *unitAdd) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
-		c.fs = c.GuessingCommand.Flags()
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
 		c.fs.StringVar(&c.process, "process", "", "Process name")
 		c.fs.StringVar(&c.process, "p", "", "Process name")
 	}
@@ -959,13 +1621,23 @@ func (c *unitAdd) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
+	return addUnits(context.Stdout, client, appName, context.Args[0], c.process)
+}
+
+// addUnits adds units to the given process of the app, streaming the output
+// of the API to w.
+func addUnits(w io.Writer, client *cmd.Client, appName, units, process string) error {
 	u, err := cmd.GetURL(fmt.Sprintf("/apps/%s/units", appName))
 	if err != nil {
 		return err
 	}
 	val := url.Values{}
-	val.Add("units", context.Args[0])
-	val.Add("process", c.process)
+	val.Add("units", units)
+	val.Add("process", process)
 	request, err := http.NewRequest("PUT", u, bytes.NewBufferString(val.Encode()))
 	if err != nil {
 		return err
@@ -976,11 +1648,12 @@ func (c *unitAdd) Run(context *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	defer response.Body.Close()
-	return cmd.StreamJSONResponse(context.Stdout, response)
+	return cmd.StreamJSONResponse(w, response)
 }
 
 type unitRemove struct {
 	cmd.GuessingCommand
+	lockWaiter
 	fs      *gnuflag.FlagSet
 	process string
 }
@@ -988,7 +1661,7 @@ type unitRemove struct {
 func (c *unitRemove) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "unit-remove",
-		Usage: "unit-remove <# of units> [-a/--app appname] [-p/-process processname]",
+		Usage: "unit-remove <# of units> [-a/--app appname] [-p/-process processname] [--wait-lock 5m]",
 		Desc: `Removes units from a process of an application. You need to have access to the
 app to be able to remove units from it.`,
 		MinArgs: 1,
@@ -997,7 +1670,7 @@ app to be able to remove units from it.`,
 
 func (c *unitRemove) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
-		c.fs = c.GuessingCommand.Flags()
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
 		c.fs.StringVar(&c.process, "process", "", "Process name")
 		c.fs.StringVar(&c.process, "p", "", "Process name")
 	}
@@ -1010,9 +1683,19 @@ func (c *unitRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
+	return removeUnits(context.Stdout, client, appName, context.Args[0], c.process)
+}
+
+// removeUnits removes units from the given process of the app, streaming the
+// output of the API to w.
+func removeUnits(w io.Writer, client *cmd.Client, appName, units, process string) error {
 	val := url.Values{}
-	val.Add("units", context.Args[0])
-	val.Add("process", c.process)
+	val.Add("units", units)
+	val.Add("process", process)
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/units?%s", appName, val.Encode()))
 	if err != nil {
 		return err
@@ -1025,7 +1708,7 @@ func (c *unitRemove) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	return cmd.StreamJSONResponse(context.Stdout, response)
+	return cmd.StreamJSONResponse(w, response)
 }
 
 type appPoolChange struct {
@@ -1046,19 +1729,24 @@ func (a *appPoolChange) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/pool", appName))
+	err = changePool(client, appName, context.Args[0])
 	if err != nil {
 		return err
 	}
-	body := bytes.NewBufferString(context.Args[0])
-	request, err := http.NewRequest("POST", url, body)
+	fmt.Fprintln(context.Stdout, "Pool successfully changed!")
+	return nil
+}
+
+func changePool(client *cmd.Client, appName, pool string) error {
+	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/pool", appName))
 	if err != nil {
 		return err
 	}
-	_, err = client.Do(request)
+	body := bytes.NewBufferString(pool)
+	request, err := http.NewRequest("POST", url, body)
 	if err != nil {
 		return err
 	}
-	fmt.Fprintln(context.Stdout, "Pool successfully changed!")
-	return nil
+	_, err = client.Do(request)
+	return err
 }
@@ -14,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	tsuruapp "github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"github.com/tsuru/tsuru/io"
@@ -827,6 +828,200 @@ App Plan:
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func appInfoDocumentTransport() http.RoundTripper {
+	return transportFunc(func(req *http.Request) (resp *http.Response, err error) {
+		var body string
+		switch req.URL.Path {
+		case "/apps/app1":
+			body = `{"name":"app1","teamowner":"myteam","cname":["app1.example.com"],"ip":"myapp.tsuru.io","platform":"php","repository":"git@git.com:php.git","units":[{"Ip":"10.10.10.10","ID":"app1/0","Status":"started","ProcessName":"web"},{"Ip":"9.9.9.9","ID":"app1/1","Status":"pending","ProcessName":"worker"}],"teams":["tsuruteam"],"owner":"myapp_owner","deploys":7,"pool":"pool1","lock":{"locked":true,"owner":"admin@example.com","reason":"POST /apps/app1/restart","acquiredate":"2015-06-01T10:32:00Z"},"plan":{"name":"test","memory":536870912,"swap":268435456,"cpushare":100,"router":"hipache","default":false}}`
+		case "/docker/node/apps/app1/containers":
+			body = `[{"ID":"app1/0","Type":"php","IP":"10.10.10.10","HostAddr":"node1","HostPort":"33001","Status":"started"}]`
+		case "/services/instances":
+			body = `[{"service":"redisapi","instances":["myredisapi"]},{"service":"mongodb","instances":[]}]`
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+}
+
+func (s *S) TestAppInfoFormatJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: appInfoDocumentTransport()}, nil, manager)
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1", "--format", "json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var doc appDocument
+	err = json.Unmarshal(stdout.Bytes(), &doc)
+	c.Assert(err, check.IsNil)
+	c.Assert(doc.Name, check.Equals, "app1")
+	c.Assert(doc.Address, check.Equals, "app1.example.com, myapp.tsuru.io")
+	c.Assert(doc.Pool, check.Equals, "pool1")
+	c.Assert(doc.Units, check.DeepEquals, map[string][]unitDoc{
+		"web":    {{ID: "app1/0", Ip: "10.10.10.10", Status: "started", ProcessName: "web"}},
+		"worker": {{ID: "app1/1", Ip: "9.9.9.9", Status: "pending", ProcessName: "worker"}},
+	})
+	c.Assert(doc.Containers, check.HasLen, 1)
+	c.Assert(doc.Containers[0].HostAddr, check.Equals, "node1")
+	c.Assert(doc.Containers[0].LastStatusUpdate, check.Equals, "")
+	c.Assert(doc.Services, check.DeepEquals, []serviceDataDoc{{Service: "redisapi", Instances: []string{"myredisapi"}}})
+	c.Assert(doc.Plan, check.DeepEquals, tsuruapp.Plan{Name: "test", Memory: 536870912, Swap: 268435456, CpuShare: 100, Router: "hipache"})
+	c.Assert(doc.Lock, check.DeepEquals, lockDoc{
+		Locked:      true,
+		Owner:       "admin@example.com",
+		Reason:      "POST /apps/app1/restart",
+		AcquireDate: "2015-06-01T10:32:00Z",
+	})
+}
+
+func (s *S) TestAppInfoFormatYAML(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: appInfoDocumentTransport()}, nil, manager)
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1", "--format", "yaml"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Matches, `(?s)name: app1\n.*units:\n  web:\n  - id: app1/0\n.*acquiredate: "?2015-06-01T10:32:00Z"?\n`)
+}
+
+func (s *S) TestAppInfoTemplate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: appInfoDocumentTransport()}, nil, manager)
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1", "--template", "{{.Ip}} {{range .Units.web}}{{.ID}}{{end}} {{.Lock.Owner}}"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "myapp.tsuru.io app1/0 admin@example.com\n")
+}
+
+func (s *S) TestAppInfoInvalidTemplate(c *check.C) {
+	context := cmd.Context{}
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1", "--template", "{{.Name"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Matches, "invalid template: .*")
+}
+
+func (s *S) TestAppInfoInvalidFormat(c *check.C) {
+	context := cmd.Context{}
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1", "--format", "xml"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `invalid format "xml", valid formats are: json, yaml`)
+	command = appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1", "--format", "json", "--template", "{{.Name}}"})
+	err = command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "the --format and --template flags can't be used together")
+}
+
+func (s *S) TestAppInfoOptionalSectionsWarnings(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	transport := transportFunc(func(req *http.Request) (resp *http.Response, err error) {
+		body, status := "", http.StatusOK
+		switch req.URL.Path {
+		case "/apps/app1":
+			body = `{"name":"app1","ip":"myapp.tsuru.io","platform":"php","units":[{"ID":"app1/0","Status":"started"}]}`
+		case "/docker/node/apps/app1/containers":
+			body, status = "You don't have permission to do this action", http.StatusForbidden
+		case "/services/instances":
+			body, status = "database is down\n", http.StatusInternalServerError
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			StatusCode: status,
+		}, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: transport}, nil, manager)
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Matches, "(?s)Application: app1\n.*app1/0.*")
+	c.Assert(stderr.String(), check.Equals, "Warning: containers: permission denied\nWarning: services: database is down\n")
+}
+
+func (s *S) TestAppInfoOptionalSectionTimeout(c *check.C) {
+	old := appInfoTimeout
+	appInfoTimeout = 50 * time.Millisecond
+	defer func() {
+		appInfoTimeout = old
+	}()
+	block := make(chan struct{})
+	defer close(block)
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	transport := transportFunc(func(req *http.Request) (resp *http.Response, err error) {
+		var body string
+		switch req.URL.Path {
+		case "/apps/app1":
+			body = `{"name":"app1","ip":"myapp.tsuru.io","platform":"php"}`
+		case "/docker/node/apps/app1/containers":
+			<-block
+		case "/services/instances":
+			body = `[{"service":"redisapi","instances":["myredisapi"]}]`
+		}
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: transport}, nil, manager)
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Matches, "(?s)Application: app1\n.*myredisapi.*")
+	c.Assert(stderr.String(), check.Equals, "Warning: containers: timed out after 50ms\n")
+}
+
+func (s *S) TestAppInfoTimeout(c *check.C) {
+	old := appInfoTimeout
+	appInfoTimeout = 50 * time.Millisecond
+	defer func() {
+		appInfoTimeout = old
+	}()
+	block := make(chan struct{})
+	defer close(block)
+	context := cmd.Context{}
+	transport := transportFunc(func(req *http.Request) (resp *http.Response, err error) {
+		<-block
+		return &http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString("")),
+			StatusCode: http.StatusOK,
+		}, nil
+	})
+	client := cmd.NewClient(&http.Client{Transport: transport}, nil, manager)
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `timed out after 50ms while loading app "app1"`)
+}
+
 func (s *S) TestAppInfoInfo(c *check.C) {
 	c.Assert((&appInfo{}).Info(), check.NotNil)
 }
@@ -1131,6 +1326,127 @@ app3
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+const appListColumnsResult = `[
+{"ip":"10.10.10.10","name":"app1","platform":"python","pool":"pool1","teamowner":"admin","plan":{"name":"small"},"units":[{"ID":"app1/0","Status":"started"},{"ID":"app1/1","Status":"error"}]},
+{"ip":"10.10.10.11","cname":["app2.tsuru.io"],"name":"app2","platform":"go","pool":"pool2","teamowner":"admin","plan":{"name":"large"},"lock":{"locked":true},"units":[{"ID":"app2/0","Status":"started"}]},
+{"ip":"10.10.10.12","name":"app3","platform":"ruby","pool":"pool1","teamowner":"team1","plan":{"name":"medium"},"units":[{"ID":"app3/0","Status":"started"}]}]`
+
+func (s *S) TestAppListColumnsAndSort(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	expected := `+-------------+----------+--------+
+| Application | Platform | Locked |
++-------------+----------+--------+
+| app3        | ruby     | false  |
++-------------+----------+--------+
+| app1        | python   | false  |
++-------------+----------+--------+
+| app2        | go       | true   |
++-------------+----------+--------+
+`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: appListColumnsResult, Status: http.StatusOK}}, nil, manager)
+	command := appList{}
+	command.Flags().Parse(true, []string{"--columns", "name,platform,locked", "--sort", "platform", "--reverse"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppListFormatCSV(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	expected := `name,pool,plan,teamowner,units,address
+app1,pool1,small,admin,1 of 2 units in-service,10.10.10.10
+app2,pool2,large,admin,1 of 1 units in-service,"app2.tsuru.io, 10.10.10.11"
+app3,pool1,medium,team1,1 of 1 units in-service,10.10.10.12
+`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: appListColumnsResult, Status: http.StatusOK}}, nil, manager)
+	command := appList{}
+	command.Flags().Parse(true, []string{"--columns", "name,pool,plan,teamowner,units,address", "--format", "csv"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppListFormatJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: appListColumnsResult, Status: http.StatusOK}}, nil, manager)
+	command := appList{}
+	command.Flags().Parse(true, []string{"--format", "json", "--sort", "units"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var apps []map[string]interface{}
+	err = json.Unmarshal(stdout.Bytes(), &apps)
+	c.Assert(err, check.IsNil)
+	c.Assert(apps, check.DeepEquals, []map[string]interface{}{
+		{
+			"name":    "app2",
+			"units":   map[string]interface{}{"available": 1.0, "total": 1.0},
+			"address": []interface{}{"app2.tsuru.io", "10.10.10.11"},
+		},
+		{
+			"name":    "app3",
+			"units":   map[string]interface{}{"available": 1.0, "total": 1.0},
+			"address": []interface{}{"10.10.10.12"},
+		},
+		{
+			"name":    "app1",
+			"units":   map[string]interface{}{"available": 1.0, "total": 2.0},
+			"address": []interface{}{"10.10.10.10"},
+		},
+	})
+}
+
+func (s *S) TestAppListClientSideFilters(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: appListColumnsResult, Status: http.StatusOK}}, nil, manager)
+	command := appList{}
+	command.Flags().Parse(true, []string{"-q", "--pool", "pool1"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app1\napp3\n")
+	stdout.Reset()
+	command = appList{}
+	command.Flags().Parse(true, []string{"-q", "--unhealthy"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "app1\n")
+}
+
+func (s *S) TestAppListInvalidColumns(c *check.C) {
+	context := cmd.Context{}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: appListColumnsResult, Status: http.StatusOK}}, nil, manager)
+	command := appList{}
+	command.Flags().Parse(true, []string{"--columns", "name,ip"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `invalid column "ip", valid columns are: name, platform, pool, plan, teamowner, units, address, locked`)
+	command = appList{}
+	command.Flags().Parse(true, []string{"--sort", "owner"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `invalid sort column "owner", valid columns are: name, platform, pool, plan, teamowner, units, address, locked`)
+	command = appList{}
+	command.Flags().Parse(true, []string{"--format", "xml"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `invalid format "xml", valid formats are: table, json, csv`)
+}
+
 func (s *S) TestAppListInfo(c *check.C) {
 	c.Assert((&appList{}).Info(), check.NotNil)
 }
@@ -1262,7 +1578,7 @@ func (s *S) TestAddCNameWithoutTheFlag(c *check.C) {
 		},
 	}
 	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
-	err := (&cnameAdd{cmd.GuessingCommand{G: fake}}).Run(&context, client)
+	err := (&cnameAdd{GuessingCommand: cmd.GuessingCommand{G: fake}}).Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(called, check.Equals, true)
 	c.Assert(stdout.String(), check.Equals, "cname successfully defined.\n")
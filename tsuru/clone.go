@@ -0,0 +1,188 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"launchpad.net/gnuflag"
+)
+
+type appClone struct {
+	cmd.ConfirmationCommand
+	lockWaiter
+	fs     *gnuflag.FlagSet
+	bind   bool
+	deploy bool
+}
+
+func (c *appClone) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-clone",
+		Usage: "app-clone <source-app> <new-app> [-b/--bind] [-d/--deploy] [-y/--assume-yes] [--wait-lock 5m]",
+		Desc: `Creates a new app with the same platform, plan, pool and team owner of an
+existing app, and copies the public environment variables of the existing app
+to the new one. Private variables are not copied, as their values can't be
+read.
+
+The [[--bind]] flag binds the new app to the same service instances the source
+app is bound to, and the [[--deploy]] flag deploys the image currently running
+in the source app to the new app.
+
+Each step is reported as it's done. If one of them fails, the command offers to
+undo the steps that were already done, unbinding the service instances and
+removing the new app. With [[--wait-lock]], the command waits for the source
+app to be unlocked before reading it.`,
+		MinArgs: 2,
+		MaxArgs: 2,
+	}
+}
+
+func (c *appClone) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(c.ConfirmationCommand.Flags(), c.lockWaiter.Flags())
+		bindMessage := "Bind the new app to the service instances of the source app"
+		c.fs.BoolVar(&c.bind, "bind", false, bindMessage)
+		c.fs.BoolVar(&c.bind, "b", false, bindMessage)
+		deployMessage := "Deploy the current image of the source app to the new app"
+		c.fs.BoolVar(&c.deploy, "deploy", false, deployMessage)
+		c.fs.BoolVar(&c.deploy, "d", false, deployMessage)
+	}
+	return c.fs
+}
+
+// cloneStep is a step of app-clone that was completed, and how to undo it.
+type cloneStep struct {
+	description string
+	undo        func() error
+}
+
+func (c *appClone) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	source, newName := context.Args[0], context.Args[1]
+	err := c.waitLock(context.Stdout, client, source)
+	if err != nil {
+		return err
+	}
+	src, err := getApp(client, source)
+	if err != nil {
+		return err
+	}
+	var services []serviceData
+	if c.bind {
+		services, err = getServiceInstances(client, source)
+		if err != nil {
+			return err
+		}
+	}
+	var image string
+	if c.deploy {
+		image, err = currentImage(client, source)
+		if err != nil {
+			return err
+		}
+		if image == "" {
+			return fmt.Errorf("app %q has no deployed image to be cloned", source)
+		}
+	}
+	var done []cloneStep
+	fail := func(err error) error {
+		return c.undo(context, newName, done, err)
+	}
+	fmt.Fprintf(context.Stdout, "==> Creating app %q (platform: %s, plan: %s, pool: %s, team owner: %s)\n",
+		newName, src.Platform, src.Plan.Name, src.Pool, src.TeamOwner)
+	create := appCreate{plan: src.Plan.Name, teamOwner: src.TeamOwner, pool: src.Pool}
+	createContext := cmd.Context{
+		Args:   []string{newName, src.Platform},
+		Stdout: context.Stdout,
+		Stderr: context.Stderr,
+		Stdin:  context.Stdin,
+	}
+	err = create.Run(&createContext, client)
+	if err != nil {
+		return err
+	}
+	done = append(done, cloneStep{
+		description: fmt.Sprintf("created app %q", newName),
+		undo: func() error {
+			return removeApp(context.Stdout, client, newName)
+		},
+	})
+	variables, err := getEnvVars(source, client)
+	if err != nil {
+		return fail(err)
+	}
+	public := make(map[string]string)
+	var private []string
+	for _, v := range variables {
+		if v.Public {
+			public[v.Name] = v.Value
+		} else {
+			private = append(private, v.Name)
+		}
+	}
+	if len(private) > 0 {
+		fmt.Fprintf(context.Stdout, "==> Skipping private variable(s) of app %q: %s\n", source, strings.Join(private, ", "))
+	}
+	if len(public) > 0 {
+		fmt.Fprintf(context.Stdout, "==> Copying %d public variable(s) from app %q\n", len(public), source)
+		err = setEnvVars(context.Stdout, client, newName, public, false)
+		if err != nil {
+			return fail(err)
+		}
+		done = append(done, cloneStep{description: fmt.Sprintf("copied %d variable(s)", len(public))})
+	}
+	for _, service := range services {
+		for _, instance := range service.Instances {
+			fmt.Fprintf(context.Stdout, "==> Binding service instance %q to app %q\n", instance, newName)
+			err = bindServiceInstance(context.Stdout, client, instance, newName)
+			if err != nil {
+				return fail(err)
+			}
+			instance := instance
+			done = append(done, cloneStep{
+				description: fmt.Sprintf("bound service instance %q", instance),
+				undo: func() error {
+					return unbindServiceInstance(context.Stdout, client, instance, newName)
+				},
+			})
+		}
+	}
+	if image != "" {
+		fmt.Fprintf(context.Stdout, "==> Deploying image %q to app %q\n", image, newName)
+		err = deployImage(context.Stdout, client, newName, image, false)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	fmt.Fprintf(context.Stdout, "App %q successfully cloned from %q.\n", newName, source)
+	return nil
+}
+
+// undo reports the failure of a step and offers to undo the steps that were
+// already done, in reverse order. It always returns the original error.
+func (c *appClone) undo(context *cmd.Context, newName string, done []cloneStep, err error) error {
+	descriptions := make([]string, len(done))
+	for i, step := range done {
+		descriptions[i] = step.description
+	}
+	fmt.Fprintf(context.Stdout, "==> Failed to clone app: %s\n", err)
+	fmt.Fprintf(context.Stdout, "Steps already done: %s.\n", strings.Join(descriptions, ", "))
+	if !c.Confirm(context, fmt.Sprintf("Undo them, removing app %q?", newName)) {
+		fmt.Fprintf(context.Stdout, "To undo them later, run: tsuru app-remove -a %s -y\n", newName)
+		return err
+	}
+	for i := len(done) - 1; i >= 0; i-- {
+		if done[i].undo == nil {
+			continue
+		}
+		if undoErr := done[i].undo(); undoErr != nil {
+			fmt.Fprintf(context.Stdout, "==> Failed to undo step %q: %s\n", done[i].description, undoErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"gopkg.in/check.v1"
+)
+
+func cloneStubAPI() *stubAPI {
+	return newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","platform":"python","pool":"pool1","teamowner":"team1","plan":{"name":"small"}}`).
+		on("GET /services/instances?app=myapp", http.StatusOK, `[{"service":"mysql","instances":["mydb"]},{"service":"redis","instances":["cache"]}]`).
+		on("GET /deploys?app=myapp&limit=10", http.StatusOK, `[
+{"Image":"tsuru/app-myapp:v2","Timestamp":"2015-06-02T10:00:00Z","Error":"build failed"},
+{"Image":"tsuru/app-myapp:v1","Timestamp":"2015-06-01T10:00:00Z"}]`).
+		on("POST /apps", http.StatusOK, `{"status":"success","repository_url":""}`).
+		on("GET /apps/myapp/env", http.StatusOK, `[{"name":"DEBUG","value":"1","public":true},{"name":"SECRET","value":"*** (private variable)","public":false}]`).
+		on("POST /apps/myapp-staging/env", http.StatusOK, `{"Message":"variable(s) successfully exported\n"}`).
+		on("PUT /services/instances/mydb/myapp-staging", http.StatusOK, `{"Message":"mydb bound\n"}`).
+		on("PUT /services/instances/cache/myapp-staging", http.StatusOK, `{"Message":"cache bound\n"}`).
+		on("POST /apps/myapp-staging/deploy", http.StatusOK, `{"Message":"deployed\n"}`).
+		on("DELETE /services/instances/mydb/myapp-staging", http.StatusOK, `{"Message":"mydb unbound\n"}`).
+		on("DELETE /apps/myapp-staging", http.StatusOK, `{"Message":"app removed\n"}`)
+}
+
+func (s *S) TestAppCloneInfo(c *check.C) {
+	c.Assert((&appClone{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppClone(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"myapp", "myapp-staging"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	api := cloneStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appClone{}
+	command.Flags().Parse(true, []string{"--bind", "--deploy"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/myapp",
+		"GET /services/instances?app=myapp",
+		"GET /deploys?app=myapp&limit=10",
+		"POST /apps",
+		"GET /apps/myapp/env",
+		"POST /apps/myapp-staging/env",
+		"PUT /services/instances/mydb/myapp-staging",
+		"PUT /services/instances/cache/myapp-staging",
+		"POST /apps/myapp-staging/deploy",
+	})
+	c.Assert(api.bodies[3], check.Matches, `.*"name":"myapp-staging".*`)
+	c.Assert(api.bodies[3], check.Matches, `.*"plan":\{"name":"small"\}.*`)
+	c.Assert(api.bodies[3], check.Matches, `.*"pool":"pool1".*"teamOwner":"team1".*`)
+	c.Assert(strings.TrimSpace(api.bodies[5]), check.Equals, `{"DEBUG":"1"}`)
+	c.Assert(api.bodies[8], check.Equals, "image=tsuru%2Fapp-myapp%3Av1")
+	expected := `==> Creating app "myapp-staging" (platform: python, plan: small, pool: pool1, team owner: team1)
+App "myapp-staging" has been created!
+Use app-info to check the status of the app and its units.
+==> Skipping private variable(s) of app "myapp": SECRET
+==> Copying 1 public variable(s) from app "myapp"
+variable(s) successfully exported
+==> Binding service instance "mydb" to app "myapp-staging"
+mydb bound
+==> Binding service instance "cache" to app "myapp-staging"
+cache bound
+==> Deploying image "tsuru/app-myapp:v1" to app "myapp-staging"
+deployed
+App "myapp-staging" successfully cloned from "myapp".
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppCloneUndo(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"myapp", "myapp-staging"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	api := cloneStubAPI()
+	api.responses["PUT /services/instances/cache/myapp-staging"] = []stubResponse{{status: http.StatusInternalServerError, body: "instance is down"}}
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appClone{}
+	command.Flags().Parse(true, []string{"--bind", "-y"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "instance is down")
+	calls := api.calls()
+	c.Assert(calls[len(calls)-2:], check.DeepEquals, []string{
+		"DELETE /services/instances/mydb/myapp-staging",
+		"DELETE /apps/myapp-staging",
+	})
+	c.Assert(stdout.String(), check.Matches, `(?s).*==> Failed to clone app: instance is down
+Steps already done: created app "myapp-staging", copied 1 variable\(s\), bound service instance "mydb".
+mydb unbound
+app removed
+`)
+}
+
+func (s *S) TestAppCloneUndoDeclined(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"myapp", "myapp-staging"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	api := cloneStubAPI()
+	api.responses["POST /apps/myapp-staging/env"] = []stubResponse{{status: http.StatusInternalServerError, body: "env failed"}}
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appClone{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	for _, call := range api.calls() {
+		c.Assert(strings.HasPrefix(call, "DELETE"), check.Equals, false)
+	}
+	c.Assert(stdout.String(), check.Matches, `(?s).*Steps already done: created app "myapp-staging".
+Undo them, removing app "myapp-staging"\? \(y/n\) Abort.
+To undo them later, run: tsuru app-remove -a myapp-staging -y
+`)
+}
+
+func (s *S) TestAppCloneWithoutImage(c *check.C) {
+	context := cmd.Context{Args: []string{"myapp", "myapp-staging"}}
+	api := cloneStubAPI()
+	api.responses["GET /deploys?app=myapp&limit=10"] = []stubResponse{{status: http.StatusNoContent}}
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appClone{}
+	command.Flags().Parse(true, []string{"--deploy"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `app "myapp" has no deployed image to be cloned`)
+	c.Assert(api.calls(), check.HasLen, 2)
+}
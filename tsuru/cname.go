@@ -0,0 +1,258 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"launchpad.net/gnuflag"
+)
+
+// verifyCNames checks that each cname resolves to the address of the app,
+// printing the result and the DNS record needed by the ones that don't. It
+// returns whether all of them point to the app.
+func verifyCNames(w io.Writer, a *app, cnames []string) bool {
+	if a.Ip == "" {
+		fmt.Fprintf(w, "App %q has no address yet, the cnames can't be verified.\n", a.Name)
+		return false
+	}
+	var missing []string
+	for _, cname := range cnames {
+		ok, detail := verifyCName(resolver(), cname, a.Ip)
+		if ok {
+			fmt.Fprintf(w, "%s points to %s.\n", cname, a.Ip)
+			continue
+		}
+		fmt.Fprintf(w, "%s doesn't point to %s: %s.\n", cname, a.Ip, detail)
+		missing = append(missing, cname)
+	}
+	if len(missing) == 0 {
+		return true
+	}
+	fmt.Fprintln(w, "Create the following DNS record(s):")
+	for _, cname := range missing {
+		fmt.Fprintf(w, "    %s\n", cnameRecord(cname, a.Ip))
+	}
+	return false
+}
+
+// verifyCName reports whether cname points to target, which is either an IP
+// address or a host name. When it doesn't, the second value says why.
+func verifyCName(r dnsResolver, cname, target string) (bool, string) {
+	targetIsIP := net.ParseIP(target) != nil
+	if !targetIsIP {
+		canonical, err := r.LookupCNAME(cname)
+		if err == nil && strings.TrimSuffix(canonical, ".") == strings.TrimSuffix(target, ".") {
+			return true, ""
+		}
+	}
+	addrs, err := r.LookupHost(cname)
+	if err != nil {
+		return false, fmt.Sprintf("it doesn't resolve (%s)", err)
+	}
+	targetAddrs := []string{target}
+	if !targetIsIP {
+		targetAddrs, err = r.LookupHost(target)
+		if err != nil {
+			return false, fmt.Sprintf("the address of the app doesn't resolve (%s)", err)
+		}
+	}
+	for _, addr := range addrs {
+		for _, targetAddr := range targetAddrs {
+			if addr == targetAddr {
+				return true, ""
+			}
+		}
+	}
+	return false, fmt.Sprintf("it resolves to %s", strings.Join(addrs, ", "))
+}
+
+// cnameRecord returns the DNS record that makes cname point to target.
+func cnameRecord(cname, target string) string {
+	name := strings.TrimSuffix(cname, ".") + "."
+	ip := net.ParseIP(target)
+	switch {
+	case ip == nil:
+		return fmt.Sprintf("%s IN CNAME %s.", name, strings.TrimSuffix(target, "."))
+	case ip.To4() == nil:
+		return fmt.Sprintf("%s IN AAAA %s", name, target)
+	default:
+		return fmt.Sprintf("%s IN A %s", name, target)
+	}
+}
+
+type cnameSync struct {
+	cmd.GuessingCommand
+	cmd.ConfirmationCommand
+	lockWaiter
+	fs   *gnuflag.FlagSet
+	file string
+}
+
+func (c *cnameSync) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "cname-sync",
+		Usage: "cname-sync -f/--file <file> [-a/--app appname] [-y/--assume-yes] [--wait-lock 5m]",
+		Desc: `Makes the cnames of an app match the ones listed in a file, adding the
+missing cnames and removing the ones that are not in the file. The file has
+one cname per line. Blank lines and lines starting with # are ignored.
+
+The changes are shown before they're made, and must be confirmed.`,
+		MinArgs: 0,
+		MaxArgs: 0,
+	}
+}
+
+func (c *cnameSync) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(
+			c.GuessingCommand.Flags(),
+			c.ConfirmationCommand.Flags(),
+		)
+		c.fs = cmd.MergeFlagSet(c.fs, c.lockWaiter.Flags())
+		fileMessage := "File with the cnames of the app, one per line"
+		c.fs.StringVar(&c.file, "file", "", fileMessage)
+		c.fs.StringVar(&c.file, "f", "", fileMessage)
+	}
+	return c.fs
+}
+
+func (c *cnameSync) Run(context *cmd.Context, client *cmd.Client) error {
+	if c.file == "" {
+		return errors.New("Please use the -f/--file flag to specify the file with the cnames.")
+	}
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	content, err := readLocalFile(c.file)
+	if err != nil {
+		return err
+	}
+	desired := parseCNameList(string(content))
+	a, err := getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	current := a.cnames()
+	toAdd := cnameDifference(desired, current)
+	toRemove := cnameDifference(current, desired)
+	if len(toAdd) == 0 && len(toRemove) == 0 {
+		fmt.Fprintf(context.Stdout, "The cnames of app %q are already up to date.\n", appName)
+		return nil
+	}
+	fmt.Fprintf(context.Stdout, "Changes to the cnames of app %q:\n", appName)
+	for _, cname := range toAdd {
+		fmt.Fprintf(context.Stdout, "  + %s\n", cname)
+	}
+	for _, cname := range toRemove {
+		fmt.Fprintf(context.Stdout, "  - %s\n", cname)
+	}
+	if !c.Confirm(context, fmt.Sprintf("Apply these changes to app %q?", appName)) {
+		return nil
+	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
+	if len(toAdd) > 0 {
+		err = addCName(toAdd, c.GuessingCommand, client)
+		if err != nil {
+			return err
+		}
+	}
+	if len(toRemove) > 0 {
+		err = unsetCName(toRemove, c.GuessingCommand, client)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(context.Stdout, "cnames successfully synchronized: %d added, %d removed.\n", len(toAdd), len(toRemove))
+	return nil
+}
+
+// parseCNameList parses a list of cnames, one per line, ignoring blank
+// lines, comments and duplicates.
+func parseCNameList(content string) []string {
+	seen := make(map[string]bool)
+	var cnames []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		cnames = append(cnames, line)
+	}
+	return cnames
+}
+
+// cnameDifference returns the cnames in a that are not in b.
+func cnameDifference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, cname := range b {
+		in[cname] = true
+	}
+	var diff []string
+	for _, cname := range a {
+		if !in[cname] {
+			diff = append(diff, cname)
+		}
+	}
+	return diff
+}
+
+type cnameList struct {
+	cmd.GuessingCommand
+	fs     *gnuflag.FlagSet
+	format string
+}
+
+func (c *cnameList) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "cname-list",
+		Usage: "cname-list [-a/--app appname] [--format table|json|yaml]",
+		Desc: `Lists the cnames of an app, one per line. With [[--format]] json or yaml,
+the cnames are printed as a list in the given format.`,
+		MinArgs: 0,
+		MaxArgs: 0,
+	}
+}
+
+func (c *cnameList) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.StringVar(&c.format, "format", "table", "Output format: table, json or yaml")
+	}
+	return c.fs
+}
+
+func (c *cnameList) Run(context *cmd.Context, client *cmd.Client) error {
+	err := checkFormat(c.format, "table", "json", "yaml")
+	if err != nil {
+		return err
+	}
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	a, err := getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	cnames := a.cnames()
+	if c.format == "json" || c.format == "yaml" {
+		return renderStructured(context.Stdout, c.format, cnames)
+	}
+	for _, cname := range cnames {
+		fmt.Fprintln(context.Stdout, cname)
+	}
+	return nil
+}
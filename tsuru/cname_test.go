@@ -0,0 +1,273 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+	"gopkg.in/yaml.v1"
+)
+
+type fakeResolver struct {
+	hosts  map[string][]string
+	cnames map[string]string
+}
+
+func (r *fakeResolver) LookupHost(host string) ([]string, error) {
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (r *fakeResolver) LookupCNAME(host string) (string, error) {
+	if cname, ok := r.cnames[host]; ok {
+		return cname, nil
+	}
+	if _, ok := r.hosts[host]; ok {
+		return host + ".", nil
+	}
+	return "", errors.New("no such host")
+}
+
+func cnameTestResolver() *fakeResolver {
+	return &fakeResolver{
+		hosts: map[string][]string{
+			"myapp.tsuru.io":   {"10.0.0.1"},
+			"www.example.com":  {"10.0.0.1"},
+			"shop.example.com": {"10.0.0.1"},
+			"old.example.com":  {"192.168.0.9"},
+		},
+		cnames: map[string]string{
+			"www.example.com": "myapp.tsuru.io.",
+		},
+	}
+}
+
+func (s *S) TestVerifyCName(c *check.C) {
+	r := cnameTestResolver()
+	ok, _ := verifyCName(r, "www.example.com", "myapp.tsuru.io")
+	c.Check(ok, check.Equals, true)
+	ok, _ = verifyCName(r, "shop.example.com", "myapp.tsuru.io")
+	c.Check(ok, check.Equals, true)
+	ok, _ = verifyCName(r, "shop.example.com", "10.0.0.1")
+	c.Check(ok, check.Equals, true)
+	ok, detail := verifyCName(r, "old.example.com", "myapp.tsuru.io")
+	c.Check(ok, check.Equals, false)
+	c.Check(detail, check.Equals, "it resolves to 192.168.0.9")
+	ok, detail = verifyCName(r, "new.example.com", "10.0.0.1")
+	c.Check(ok, check.Equals, false)
+	c.Check(detail, check.Equals, "it doesn't resolve (no such host)")
+}
+
+func (s *S) TestCNameRecord(c *check.C) {
+	c.Check(cnameRecord("www.example.com", "myapp.tsuru.io"), check.Equals, "www.example.com. IN CNAME myapp.tsuru.io.")
+	c.Check(cnameRecord("www.example.com", "10.0.0.1"), check.Equals, "www.example.com. IN A 10.0.0.1")
+	c.Check(cnameRecord("www.example.com.", "2001:db8::1"), check.Equals, "www.example.com. IN AAAA 2001:db8::1")
+}
+
+func (s *S) TestAddCNameVerify(c *check.C) {
+	dnsResolv = cnameTestResolver()
+	defer func() {
+		dnsResolv = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"www.example.com", "shop.example.com"},
+	}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","ip":"myapp.tsuru.io"}`).
+		on("POST /apps/myapp/cname", http.StatusOK, "")
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := cnameAdd{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--verify"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{"GET /apps/myapp", "POST /apps/myapp/cname"})
+	c.Assert(stdout.String(), check.Equals, `www.example.com points to myapp.tsuru.io.
+shop.example.com points to myapp.tsuru.io.
+cname successfully defined.
+`)
+}
+
+func (s *S) TestAddCNameVerifyMismatch(c *check.C) {
+	dnsResolv = cnameTestResolver()
+	defer func() {
+		dnsResolv = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"www.example.com", "old.example.com", "new.example.com"},
+	}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","ip":"myapp.tsuru.io"}`).
+		on("POST /apps/myapp/cname", http.StatusOK, "")
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := cnameAdd{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--verify"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "some cnames don't point to the app, .*")
+	c.Assert(api.calls(), check.DeepEquals, []string{"GET /apps/myapp"})
+	c.Assert(stdout.String(), check.Equals, `www.example.com points to myapp.tsuru.io.
+old.example.com doesn't point to myapp.tsuru.io: it resolves to 192.168.0.9.
+new.example.com doesn't point to myapp.tsuru.io: it doesn't resolve (no such host).
+Create the following DNS record(s):
+    old.example.com. IN CNAME myapp.tsuru.io.
+    new.example.com. IN CNAME myapp.tsuru.io.
+`)
+}
+
+func (s *S) TestAddCNameVerifyMismatchForce(c *check.C) {
+	dnsResolv = cnameTestResolver()
+	defer func() {
+		dnsResolv = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"new.example.com"},
+	}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","ip":"10.0.0.1"}`).
+		on("POST /apps/myapp/cname", http.StatusOK, "")
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := cnameAdd{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--verify", "-f"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{"GET /apps/myapp", "POST /apps/myapp/cname"})
+	c.Assert(stdout.String(), check.Matches, `(?s).*    new.example.com. IN A 10.0.0.1
+Warning: adding the cnames anyway, as requested with --force.
+cname successfully defined.
+`)
+}
+
+func (s *S) TestCNameSync(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("y\n"),
+	}
+	fsystem = &fstest.RecordingFs{FileContent: "# vanity domains\nwww.example.com\n\nshop.example.com\nwww.example.com\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","cname":["www.example.com","old.example.com"]}`).
+		on("POST /apps/myapp/cname", http.StatusOK, "").
+		on("DELETE /apps/myapp/cname", http.StatusOK, "")
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := cnameSync{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "-f", "domains.txt"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{"GET /apps/myapp", "POST /apps/myapp/cname", "DELETE /apps/myapp/cname"})
+	c.Assert(api.bodies[1], check.Equals, `{"cname":["shop.example.com"]}`)
+	c.Assert(api.bodies[2], check.Equals, `{"cname":["old.example.com"]}`)
+	c.Assert(stdout.String(), check.Equals, `Changes to the cnames of app "myapp":
+  + shop.example.com
+  - old.example.com
+Apply these changes to app "myapp"? (y/n) cnames successfully synchronized: 1 added, 1 removed.
+`)
+}
+
+func (s *S) TestCNameSyncUpToDate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	fsystem = &fstest.RecordingFs{FileContent: "www.example.com\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	api := newStubAPI().on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","cname":["www.example.com"]}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := cnameSync{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "-f", "domains.txt"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "The cnames of app \"myapp\" are already up to date.\n")
+}
+
+func (s *S) TestCNameSyncWithoutFile(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	command := cnameSync{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "Please use the -f/--file flag .*")
+}
+
+func (s *S) TestCNameList(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","cname":["www.example.com","","shop.example.com"]}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := cnameList{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "www.example.com\nshop.example.com\n")
+	stdout.Reset()
+	command = cnameList{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--format", "json"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var cnames []string
+	err = json.Unmarshal(stdout.Bytes(), &cnames)
+	c.Assert(err, check.IsNil)
+	c.Assert(cnames, check.DeepEquals, []string{"www.example.com", "shop.example.com"})
+	stdout.Reset()
+	command = cnameList{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--format", "yaml"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	cnames = nil
+	err = yaml.Unmarshal(stdout.Bytes(), &cnames)
+	c.Assert(err, check.IsNil)
+	c.Assert(cnames, check.DeepEquals, []string{"www.example.com", "shop.example.com"})
+}
+
+func (s *S) TestCNameListEmpty(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /apps/myapp", http.StatusOK, `{"name":"myapp"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := cnameList{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
+func (s *S) TestCNameListInvalidFormat(c *check.C) {
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	command := cnameList{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--format", "text"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `invalid format "text", valid formats are: table, json, yaml`)
+}
+
+func (s *S) TestCNameListEmptyJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /apps/myapp", http.StatusOK, `{"name":"myapp"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := cnameList{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--format", "json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "[]\n")
+}
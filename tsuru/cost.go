@@ -0,0 +1,261 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	tsuruapp "github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/cmd"
+	"gopkg.in/yaml.v1"
+	"launchpad.net/gnuflag"
+)
+
+// planPrices holds the monthly price of one unit of each plan, as read from
+// the local price file (see planCompare's description for its format).
+type planPrices struct {
+	Currency string             `yaml:"currency"`
+	Prices   map[string]float64 `yaml:"prices"`
+}
+
+func planPricesPath() string {
+	return cmd.JoinWithUserDir(".tsuru", "plan-prices.yaml")
+}
+
+// readPlanPrices reads the price file. A missing file results in an empty
+// list of prices.
+func readPlanPrices() (*planPrices, error) {
+	path := planPricesPath()
+	prices := planPrices{Prices: map[string]float64{}}
+	f, err := filesystem().Open(path)
+	if os.IsNotExist(err) {
+		return &prices, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(content, &prices)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price file %q: %s", path, err)
+	}
+	for plan, price := range prices.Prices {
+		if price < 0 {
+			return nil, fmt.Errorf("invalid price file %q: negative price for plan %q", path, plan)
+		}
+	}
+	return &prices, nil
+}
+
+// price returns the monthly price of one unit of the plan.
+func (p *planPrices) price(plan string) (float64, bool) {
+	price, ok := p.Prices[plan]
+	return price, ok
+}
+
+func (p *planPrices) format(value float64) string {
+	if p.Currency == "" {
+		return fmt.Sprintf("%.2f", value)
+	}
+	return fmt.Sprintf("%s %.2f", p.Currency, value)
+}
+
+type planCompare struct{}
+
+func (c *planCompare) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "plan-compare",
+		Usage: "plan-compare <plan_name> [plan_name] ...",
+		Desc: `Compares the given plans, showing their memory, swap, CPU share and router
+next to the price of one unit per month.
+
+Prices are read from the file [[~/.tsuru/plan-prices.yaml]], in the format:
+
+::
+
+    currency: USD
+    prices:
+      small: 7.5
+      large: 30
+
+Plans without a price in the file are shown with "-".`,
+		MinArgs: 1,
+	}
+}
+
+func (c *planCompare) Run(context *cmd.Context, client *cmd.Client) error {
+	plans, err := listPlans(client)
+	if err != nil {
+		return err
+	}
+	prices, err := readPlanPrices()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]tsuruapp.Plan, len(plans))
+	for _, p := range plans {
+		byName[p.Name] = p
+	}
+	table := cmd.NewTable()
+	table.Headers = cmd.Row{"Name", "Memory", "Swap", "Cpu Share", "Router", "Price per unit/month"}
+	var missing []string
+	for _, name := range context.Args {
+		p, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("plan %q not found, run plan-list to see the available plans", name)
+		}
+		price := "-"
+		if value, ok := prices.price(name); ok {
+			price = prices.format(value)
+		} else {
+			missing = append(missing, name)
+		}
+		table.AddRow(cmd.Row{p.Name, humanSize(p.Memory), humanSize(p.Swap), strconv.Itoa(p.CpuShare), p.Router, price})
+	}
+	fmt.Fprint(context.Stdout, table.String())
+	if len(missing) > 0 {
+		fmt.Fprintf(context.Stdout, "No price for %s in %s.\n", strings.Join(missing, ", "), planPricesPath())
+	}
+	return nil
+}
+
+type appCost struct {
+	cmd.GuessingCommand
+	fs   *gnuflag.FlagSet
+	team string
+}
+
+func (c *appCost) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-cost",
+		Usage: "app-cost [-a/--app appname] [-t/--team teamname]",
+		Desc: `Estimates the monthly cost of an app, multiplying the price of its plan by
+the number of units of each process.
+
+With [[--team]], estimates the cost of all apps owned by the team and shows the
+total.
+
+Prices are read from the file [[~/.tsuru/plan-prices.yaml]]. See plan-compare
+for its format.`,
+		MinArgs: 0,
+	}
+}
+
+func (c *appCost) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		team := "Estimate the cost of all apps owned by the team"
+		c.fs.StringVar(&c.team, "team", "", team)
+		c.fs.StringVar(&c.team, "t", "", team)
+	}
+	return c.fs
+}
+
+func (c *appCost) Run(context *cmd.Context, client *cmd.Client) error {
+	prices, err := readPlanPrices()
+	if err != nil {
+		return err
+	}
+	if len(prices.Prices) == 0 {
+		return fmt.Errorf("no prices found in %s, run plan-compare --help to see its format", planPricesPath())
+	}
+	if c.team != "" {
+		return c.teamCost(context, client, prices)
+	}
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	a, err := getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	price, ok := prices.price(a.Plan.Name)
+	if !ok {
+		return fmt.Errorf("no price for plan %q in %s", a.Plan.Name, planPricesPath())
+	}
+	counts := unitCounts(a.Units)
+	processes := make([]string, 0, len(counts))
+	for process := range counts {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+	var total int
+	table := cmd.NewTable()
+	table.Headers = cmd.Row{"Process", "Units", "Cost per month"}
+	for _, process := range processes {
+		total += counts[process]
+		table.AddRow(cmd.Row{process, strconv.Itoa(counts[process]), prices.format(price * float64(counts[process]))})
+	}
+	fmt.Fprintf(context.Stdout, "App %q uses plan %q, at %s per unit per month.\n", a.Name, a.Plan.Name, prices.format(price))
+	fmt.Fprint(context.Stdout, table.String())
+	fmt.Fprintf(context.Stdout, "Total: %s per month.\n", prices.format(price*float64(total)))
+	return nil
+}
+
+// teamCost shows the cost of each app owned by the team and their total.
+// Apps whose plan has no price are left out of the total.
+func (c *appCost) teamCost(context *cmd.Context, client *cmd.Client, prices *planPrices) error {
+	list := appList{filter: appFilter{teamOwner: c.team}}
+	result, err := list.fetch(client)
+	if err != nil {
+		return err
+	}
+	var apps []app
+	if result != nil {
+		err = json.Unmarshal(result, &apps)
+		if err != nil {
+			return err
+		}
+	}
+	if len(apps) == 0 {
+		fmt.Fprintf(context.Stdout, "Team %q doesn't own any apps.\n", c.team)
+		return nil
+	}
+	sort.Sort(appsByColumn{apps: apps, column: appListColumns["name"]})
+	table := cmd.NewTable()
+	table.Headers = cmd.Row{"App", "Plan", "Units", "Cost per month"}
+	var (
+		total   float64
+		missing []string
+	)
+	for _, a := range apps {
+		units := countUnits(a.Units)
+		cost := "-"
+		if price, ok := prices.price(a.Plan.Name); ok {
+			value := price * float64(units)
+			total += value
+			cost = prices.format(value)
+		} else {
+			missing = append(missing, a.Name)
+		}
+		table.AddRow(cmd.Row{a.Name, a.Plan.Name, strconv.Itoa(units), cost})
+	}
+	fmt.Fprint(context.Stdout, table.String())
+	fmt.Fprintf(context.Stdout, "Total for team %q: %s per month.\n", c.team, prices.format(total))
+	if len(missing) > 0 {
+		fmt.Fprintf(context.Stdout, "Not included in the total, their plans have no price in %s: %s.\n", planPricesPath(), strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// countUnits returns the number of units, leaving out the ones without an ID,
+// like unitCounts.
+func countUnits(units []unit) int {
+	var total int
+	for _, n := range unitCounts(units) {
+		total += n
+	}
+	return total
+}
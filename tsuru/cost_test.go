@@ -0,0 +1,170 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+const planPricesFile = "currency: USD\nprices:\n  small: 7.5\n  hiperplan: 30\n"
+
+func (s *S) TestPlanCompareInfo(c *check.C) {
+	c.Assert((&planCompare{}).Info(), check.NotNil)
+}
+
+func (s *S) TestPlanCompare(c *check.C) {
+	rfs := &fstest.RecordingFs{FileContent: planPricesFile}
+	fsystem = rfs
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Args: []string{"hiperplan", "vulcan", "small"}, Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /plans", http.StatusOK, planChangePlans)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := planCompare{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(rfs.HasAction("open "+cmd.JoinWithUserDir(".tsuru", "plan-prices.yaml")), check.Equals, true)
+	expected := `+-----------+---------+--------+-----------+---------+----------------------+
+| Name      | Memory  | Swap   | Cpu Share | Router  | Price per unit/month |
++-----------+---------+--------+-----------+---------+----------------------+
+| hiperplan | 1024 MB | 512 MB | 200       | hipache | USD 30.00            |
+| vulcan    | 1024 MB | 512 MB | 200       | vulcand | -                    |
+| small     | 256 MB  | 128 MB | 50        | hipache | USD 7.50             |
++-----------+---------+--------+-----------+---------+----------------------+
+No price for vulcan in ` + cmd.JoinWithUserDir(".tsuru", "plan-prices.yaml") + ".\n"
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestPlanCompareWithoutPriceFile(c *check.C) {
+	fsystem = &fstest.FileNotFoundFs{}
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Args: []string{"small"}, Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /plans", http.StatusOK, planChangePlans)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	err := (&planCompare{}).Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Matches, `(?s).*\| small +\| 256 MB +\| 128 MB +\| 50 +\| hipache +\| - +\|.*No price for small in .*`)
+}
+
+func (s *S) TestPlanCompareUnknownPlan(c *check.C) {
+	fsystem = &fstest.RecordingFs{FileContent: planPricesFile}
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Args: []string{"small", "huge"}, Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /plans", http.StatusOK, planChangePlans)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	err := (&planCompare{}).Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `plan "huge" not found, run plan-list to see the available plans`)
+}
+
+func (s *S) TestReadPlanPricesNegative(c *check.C) {
+	fsystem = &fstest.RecordingFs{FileContent: "prices:\n  small: -1\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	_, err := readPlanPrices()
+	c.Assert(err, check.ErrorMatches, `invalid price file ".*plan-prices.yaml": negative price for plan "small"`)
+}
+
+func (s *S) TestAppCostInfo(c *check.C) {
+	c.Assert((&appCost{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppCost(c *check.C) {
+	fsystem = &fstest.RecordingFs{FileContent: planPricesFile}
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","plan":{"name":"hiperplan"},"units":[
+{"ID":"u1","ProcessName":"web"},{"ID":"u2","ProcessName":"web"},{"ID":"u3","ProcessName":"worker"},{"ID":""}]}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appCost{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `App "myapp" uses plan "hiperplan", at USD 30.00 per unit per month.
++---------+-------+----------------+
+| Process | Units | Cost per month |
++---------+-------+----------------+
+| web     | 2     | USD 60.00      |
+| worker  | 1     | USD 30.00      |
++---------+-------+----------------+
+Total: USD 90.00 per month.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppCostPlanWithoutPrice(c *check.C) {
+	fsystem = &fstest.RecordingFs{FileContent: planPricesFile}
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","plan":{"name":"vulcan"}}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appCost{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `no price for plan "vulcan" in .*plan-prices.yaml`)
+}
+
+func (s *S) TestAppCostWithoutPriceFile(c *check.C) {
+	fsystem = &fstest.FileNotFoundFs{}
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appCost{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `no prices found in .*plan-prices.yaml, run plan-compare --help to see its format`)
+	c.Assert(api.calls(), check.HasLen, 0)
+}
+
+func (s *S) TestAppCostTeam(c *check.C) {
+	fsystem = &fstest.RecordingFs{FileContent: planPricesFile}
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /apps?teamowner=myteam", http.StatusOK, `[
+{"name":"web","plan":{"name":"small"},"units":[{"ID":"u1"},{"ID":"u2"},{"ID":""}]},
+{"name":"api","plan":{"name":"hiperplan"},"units":[{"ID":"u3"}]},
+{"name":"search","plan":{"name":"vulcan"},"units":[{"ID":"u4"}]}]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appCost{}
+	command.Flags().Parse(true, []string{"--team", "myteam"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `+--------+-----------+-------+----------------+
+| App    | Plan      | Units | Cost per month |
++--------+-----------+-------+----------------+
+| api    | hiperplan | 1     | USD 30.00      |
+| search | vulcan    | 1     | -              |
+| web    | small     | 2     | USD 15.00      |
++--------+-----------+-------+----------------+
+Total for team "myteam": USD 45.00 per month.
+Not included in the total, their plans have no price in ` + cmd.JoinWithUserDir(".tsuru", "plan-prices.yaml") + ": search.\n"
+	c.Assert(stdout.String(), check.Equals, expected)
+}
@@ -0,0 +1,438 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	tsuruapp "github.com/tsuru/tsuru/app"
+	"github.com/tsuru/tsuru/cmd"
+	tsuruIo "github.com/tsuru/tsuru/io"
+	"golang.org/x/crypto/ssh/terminal"
+	"launchpad.net/gnuflag"
+)
+
+const dashboardHelp = "j/k or arrows: select app   r: restart   +: add unit   q: quit"
+
+type appDashboard struct {
+	fs       *gnuflag.FlagSet
+	team     string
+	interval time.Duration
+	lines    int
+}
+
+func (c *appDashboard) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-dashboard",
+		Usage: "app-dashboard [-t/--team teamname] [--interval 5s] [-l/--lines numberOfLines]",
+		Desc: `Shows a live view of the apps you have access to, or of the apps owned by the
+team given in the [[--team]] flag. For each app it displays the state of the
+units of each process and the lock, along with the units, the last deploy and
+the latest log entries of the selected app.
+
+The view is refreshed every [[--interval]] (5 seconds by default). Use the
+arrows or j/k to select an app, "r" to restart it, "+" to add a unit to it
+and "q" to quit. Restarts and new units must be confirmed with "y".
+
+The [[--lines]] flag sets how many log entries are displayed, by default 10.`,
+		MinArgs: 0,
+	}
+}
+
+func (c *appDashboard) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("app-dashboard", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.team, "team", "", "Display only applications owned by the given team")
+		c.fs.StringVar(&c.team, "t", "", "Display only applications owned by the given team")
+		c.fs.DurationVar(&c.interval, "interval", 5*time.Second, "Interval between refreshes")
+		c.fs.IntVar(&c.lines, "lines", 10, "The number of log entries to display")
+		c.fs.IntVar(&c.lines, "l", 10, "The number of log entries to display")
+	}
+	return c.fs
+}
+
+func (c *appDashboard) Run(context *cmd.Context, client *cmd.Client) error {
+	stdin, ok := context.Stdin.(*os.File)
+	if !ok || !terminal.IsTerminal(int(stdin.Fd())) {
+		return errors.New("app-dashboard must be run in an interactive terminal")
+	}
+	state, err := terminal.MakeRaw(int(stdin.Fd()))
+	if err != nil {
+		return err
+	}
+	defer terminal.Restore(int(stdin.Fd()), state)
+	interval := c.interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	out := crlfWriter{w: context.Stdout}
+	keys := make(chan string)
+	go readKeys(stdin, keys)
+	d := newDashboard(client, c.team, c.lines)
+	for {
+		d.refresh()
+		d.render(out)
+		tick := watchTick(interval)
+	wait:
+		for {
+			select {
+			case key, ok := <-keys:
+				if !ok || d.handleKey(key) {
+					fmt.Fprint(out, clearScreen)
+					return nil
+				}
+				d.render(out)
+			case <-tick:
+				break wait
+			}
+		}
+	}
+}
+
+// crlfWriter translates line feeds into carriage return plus line feed, as
+// the terminal doesn't do it in raw mode.
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (w crlfWriter) Write(p []byte) (int, error) {
+	_, err := w.w.Write(bytes.Replace(p, []byte("\n"), []byte("\r\n"), -1))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// readKeys reads key presses from r and sends them to keys, translating
+// escape sequences of arrow keys to "up" and "down" and Ctrl-C to "ctrl-c".
+// keys is closed when r can't be read anymore.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 16)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		input := buf[:n]
+		for len(input) > 0 {
+			switch {
+			case bytes.HasPrefix(input, []byte("\033[A")):
+				keys <- "up"
+				input = input[3:]
+			case bytes.HasPrefix(input, []byte("\033[B")):
+				keys <- "down"
+				input = input[3:]
+			case input[0] == 3:
+				keys <- "ctrl-c"
+				input = input[1:]
+			default:
+				keys <- string(input[0])
+				input = input[1:]
+			}
+		}
+	}
+}
+
+// dashboard holds the state of app-dashboard. It's kept apart from the
+// terminal handling, so it can be driven by tests.
+type dashboard struct {
+	client   *cmd.Client
+	team     string
+	logLines int
+	apps     []app
+	deploy   *tsuruapp.DeployData
+	logs     string
+	selected string
+	pending  string
+	status   string
+	err      error
+}
+
+func newDashboard(client *cmd.Client, team string, logLines int) *dashboard {
+	return &dashboard{client: client, team: team, logLines: logLines}
+}
+
+// refresh reloads the apps, and the last deploy and the log of the selected
+// app. Deploys are only loaded for the selected app, so a refresh makes the
+// same number of requests regardless of the number of apps.
+func (d *dashboard) refresh() {
+	d.err = d.loadApps()
+	d.loadSelected()
+}
+
+func (d *dashboard) loadApps() error {
+	filter := appFilter{teamOwner: d.team}
+	qs, err := filter.queryString(d.client)
+	if err != nil {
+		return err
+	}
+	body, err := dashboardGet(d.client, fmt.Sprintf("/apps?%s", qs.Encode()))
+	if err != nil {
+		return err
+	}
+	var apps []app
+	if body != nil {
+		err = json.Unmarshal(body, &apps)
+		if err != nil {
+			return err
+		}
+	}
+	sort.Sort(appsByColumn{apps: apps, column: appListColumns["name"]})
+	d.apps = apps
+	if d.selectedIndex() < 0 {
+		d.selected = ""
+		if len(apps) > 0 {
+			d.selected = apps[0].Name
+		}
+	}
+	return nil
+}
+
+// loadSelected loads the last deploy and the log of the selected app.
+func (d *dashboard) loadSelected() {
+	d.deploy = nil
+	d.logs = ""
+	if d.selected == "" {
+		return
+	}
+	if body, err := dashboardGet(d.client, fmt.Sprintf("/deploys?app=%s&limit=1", d.selected)); err == nil && body != nil {
+		var deploys []tsuruapp.DeployData
+		if json.Unmarshal(body, &deploys) == nil && len(deploys) > 0 {
+			sort.Sort(sort.Reverse(deployList(deploys)))
+			d.deploy = &deploys[0]
+		}
+	}
+	body, err := dashboardGet(d.client, fmt.Sprintf("/apps/%s/log?lines=%d", d.selected, d.logLines))
+	if err != nil {
+		d.logs = fmt.Sprintf("Unable to load the log: %s\n", err)
+		return
+	}
+	var buf bytes.Buffer
+	w := tsuruIo.NewStreamWriter(&buf, logFormatter{})
+	w.Write(body)
+	d.logs = buf.String()
+}
+
+func (d *dashboard) selectedIndex() int {
+	for i, a := range d.apps {
+		if a.Name == d.selected {
+			return i
+		}
+	}
+	return -1
+}
+
+func (d *dashboard) move(delta int) {
+	if len(d.apps) == 0 {
+		return
+	}
+	i := d.selectedIndex() + delta
+	if i < 0 || i >= len(d.apps) {
+		return
+	}
+	d.selected = d.apps[i].Name
+	d.loadSelected()
+}
+
+// handleKey handles a key press, returning true when the dashboard should be
+// closed. Actions on apps are only run after confirmed with "y".
+func (d *dashboard) handleKey(key string) bool {
+	if d.pending != "" {
+		action := d.pending
+		d.pending = ""
+		if key == "y" || key == "Y" {
+			d.run(action)
+		} else {
+			d.status = "Cancelled."
+		}
+		return false
+	}
+	switch key {
+	case "q", "ctrl-c":
+		return true
+	case "j", "down":
+		d.move(1)
+	case "k", "up":
+		d.move(-1)
+	case "r":
+		if d.selected != "" {
+			d.pending = "restart"
+		}
+	case "+":
+		if d.selected != "" {
+			d.pending = "unit-add"
+		}
+	}
+	return false
+}
+
+func (d *dashboard) run(action string) {
+	var (
+		buf bytes.Buffer
+		err error
+	)
+	switch action {
+	case "restart":
+		err = restartApp(&buf, d.client, d.selected, "")
+	case "unit-add":
+		err = addUnits(&buf, d.client, d.selected, "1", "")
+	}
+	if err != nil {
+		d.status = fmt.Sprintf("Error: %s", err)
+	} else {
+		d.status = lastLine(buf.String())
+	}
+	d.refresh()
+}
+
+func (d *dashboard) render(w io.Writer) {
+	var buf bytes.Buffer
+	buf.WriteString(clearScreen)
+	if d.team != "" {
+		fmt.Fprintf(&buf, "Apps of team %s\n\n", d.team)
+	} else {
+		buf.WriteString("Apps\n\n")
+	}
+	if d.err != nil {
+		fmt.Fprintf(&buf, "Error: %s\n\n", d.err)
+	}
+	table := cmd.NewTable()
+	table.Headers = cmd.Row([]string{"", "Application", "Units", "Lock"})
+	for i := range d.apps {
+		a := &d.apps[i]
+		var marker string
+		if a.Name == d.selected {
+			marker = ">"
+		}
+		var lockStatus string
+		if a.Lock.Locked {
+			lockStatus = fmt.Sprintf("locked by %s", a.Lock.Owner)
+		}
+		table.AddRow(cmd.Row([]string{marker, a.Name, unitsPerProcess(a.Units), lockStatus}))
+	}
+	buf.Write(table.Bytes())
+	if i := d.selectedIndex(); i >= 0 {
+		a := &d.apps[i]
+		if a.Lock.Locked {
+			fmt.Fprintf(&buf, "\n%s\n", a.Lock.String())
+		}
+		if deploy := d.lastDeploy(); deploy != "" {
+			fmt.Fprintf(&buf, "\nLast deploy of %s: %s\n", a.Name, deploy)
+		}
+		unitsTable := cmd.NewTable()
+		unitsTable.Headers = cmd.Row([]string{"Unit", "Process", "State"})
+		for _, u := range a.Units {
+			if u.ID == "" {
+				continue
+			}
+			id := u.ID
+			if len(id) > 10 {
+				id = id[:10]
+			}
+			unitsTable.AddRow(cmd.Row([]string{id, u.ProcessName, u.Status}))
+		}
+		if unitsTable.Rows() > 0 {
+			fmt.Fprintf(&buf, "\nUnits of %s:\n", a.Name)
+			buf.Write(unitsTable.Bytes())
+		}
+		fmt.Fprintf(&buf, "\nLog of %s:\n%s", a.Name, d.logs)
+	}
+	buf.WriteString("\n")
+	switch d.pending {
+	case "restart":
+		fmt.Fprintf(&buf, "Restart app %q? (y/n)\n", d.selected)
+	case "unit-add":
+		fmt.Fprintf(&buf, "Add a unit to app %q? (y/n)\n", d.selected)
+	default:
+		if d.status != "" {
+			fmt.Fprintf(&buf, "%s\n", d.status)
+		}
+		fmt.Fprintf(&buf, "%s\n", dashboardHelp)
+	}
+	w.Write(buf.Bytes())
+}
+
+func (d *dashboard) lastDeploy() string {
+	deploy := d.deploy
+	if deploy == nil {
+		return ""
+	}
+	text := deploy.Timestamp.Local().Format(time.Stamp)
+	if deploy.Image != "" {
+		text = fmt.Sprintf("%s (%s)", text, deploy.Image)
+	}
+	if deploy.Error != "" {
+		text = cmd.Colorfy(text, "red", "", "")
+	}
+	return text
+}
+
+// unitsPerProcess summarizes the units of each process, as in
+// "web: 2/3, worker: 1/1", where the first number is the number of
+// available units.
+func unitsPerProcess(units []unit) string {
+	available := make(map[string]int)
+	total := make(map[string]int)
+	for _, u := range units {
+		if u.ID == "" {
+			continue
+		}
+		total[u.ProcessName]++
+		if u.Available() {
+			available[u.ProcessName]++
+		}
+	}
+	processes := make([]string, 0, len(total))
+	for process := range total {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+	parts := make([]string, len(processes))
+	for i, process := range processes {
+		parts[i] = fmt.Sprintf("%d/%d", available[process], total[process])
+		if process != "" {
+			parts[i] = fmt.Sprintf("%s: %s", process, parts[i])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// dashboardGet returns the body of a GET request to the given path, or nil if
+// the API returns no content.
+func dashboardGet(client *cmd.Client, path string) ([]byte, error) {
+	url, err := cmd.GetURL(path)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	return ioutil.ReadAll(response.Body)
+}
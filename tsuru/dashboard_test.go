@@ -0,0 +1,150 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"gopkg.in/check.v1"
+)
+
+func dashboardStubAPI() *stubAPI {
+	return newStubAPI().
+		on("GET /apps", http.StatusOK, `[{"name":"app2","ip":"app2.tsuru.io","units":[{"ID":"app2/0","Status":"error","ProcessName":"web"}]},
+{"name":"app1","ip":"app1.tsuru.io","lock":{"locked":true,"owner":"admin@example.com","reason":"POST /apps/app1/restart"},"units":[{"ID":"app1/0","Status":"started","ProcessName":"web"},{"ID":"app1/1","Status":"started","ProcessName":"web"},{"ID":"app1/2","Status":"pending","ProcessName":"worker"}]}]`).
+		on("GET /deploys?app=app1&limit=1", http.StatusOK, `[{"ID":"1","App":"app1","Timestamp":"2015-06-01T10:00:00Z","Image":"tsuru/app-app1:v3"}]`).
+		on("GET /deploys?app=app2&limit=1", http.StatusNoContent, "").
+		on("GET /apps/app1/log", http.StatusOK, `[{"Date":"2015-06-01T10:00:00Z","Message":"hello from app1","Source":"app","Unit":"app1/0"}]`).
+		on("GET /apps/app2/log", http.StatusOK, `[{"Date":"2015-06-01T10:00:00Z","Message":"hello from app2","Source":"app","Unit":"app2/0"}]`).
+		on("POST /apps/app1/restart", http.StatusOK, `{"Message":"---> Restarting\n"}`+"\n"+`{"Message":"---> Restarted\n"}`).
+		on("PUT /apps/app2/units", http.StatusOK, `{"Message":"---> Unit added\n"}`)
+}
+
+func (s *S) TestAppDashboardInfo(c *check.C) {
+	c.Assert((&appDashboard{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppDashboardRequiresTerminal(c *check.C) {
+	context := cmd.Context{Stdin: bytes.NewBufferString("")}
+	command := appDashboard{}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "app-dashboard must be run in an interactive terminal")
+}
+
+func (s *S) TestDashboardRefreshAndRender(c *check.C) {
+	api := dashboardStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	d := newDashboard(client, "myteam", 5)
+	d.refresh()
+	c.Assert(d.err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps?teamowner=myteam",
+		"GET /deploys?app=app1&limit=1",
+		"GET /apps/app1/log?lines=5",
+	})
+	var buf bytes.Buffer
+	d.render(&buf)
+	out := buf.String()
+	c.Assert(strings.HasPrefix(out, clearScreen+"Apps of team myteam\n\n"), check.Equals, true)
+	c.Assert(out, check.Matches, `(?s).*\| > \| app1 +\| web: 2/2, worker: 0/1 \| locked by admin@example.com \|.*`)
+	c.Assert(out, check.Matches, `(?s).*\|   \| app2 +\| web: 0/1 +\| +\|.*`)
+	c.Assert(out, check.Matches, `(?s).*Last deploy of app1: .* \(tsuru/app-app1:v3\)\n.*`)
+	c.Assert(out, check.Matches, `(?s).*Units of app1:\n.*\| app1/2 \| worker +\| pending \|.*`)
+	c.Assert(out, check.Matches, `(?s).*Log of app1:\n.*hello from app1\n.*`)
+	c.Assert(strings.HasSuffix(out, dashboardHelp+"\n"), check.Equals, true)
+}
+
+func (s *S) TestDashboardSelection(c *check.C) {
+	api := dashboardStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	d := newDashboard(client, "", 10)
+	d.refresh()
+	api.reset()
+	c.Assert(d.handleKey("down"), check.Equals, false)
+	c.Assert(d.selected, check.Equals, "app2")
+	c.Assert(strings.Contains(d.logs, "hello from app2"), check.Equals, true)
+	c.Assert(d.handleKey("j"), check.Equals, false)
+	c.Assert(d.selected, check.Equals, "app2")
+	c.Assert(d.handleKey("k"), check.Equals, false)
+	c.Assert(d.selected, check.Equals, "app1")
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /deploys?app=app2&limit=1",
+		"GET /apps/app2/log?lines=10",
+		"GET /deploys?app=app1&limit=1",
+		"GET /apps/app1/log?lines=10",
+	})
+	c.Assert(d.handleKey("q"), check.Equals, true)
+	c.Assert(d.handleKey("ctrl-c"), check.Equals, true)
+}
+
+func (s *S) TestDashboardRestart(c *check.C) {
+	api := dashboardStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	d := newDashboard(client, "", 10)
+	d.refresh()
+	api.reset()
+	d.handleKey("r")
+	var buf bytes.Buffer
+	d.render(&buf)
+	c.Assert(strings.HasSuffix(buf.String(), "Restart app \"app1\"? (y/n)\n"), check.Equals, true)
+	c.Assert(api.calls(), check.HasLen, 0)
+	d.handleKey("y")
+	calls := api.calls()
+	c.Assert(calls[0], check.Equals, "POST /apps/app1/restart?process=")
+	c.Assert(calls[1], check.Equals, "GET /apps")
+	c.Assert(d.status, check.Equals, "---> Restarted")
+}
+
+func (s *S) TestDashboardUnitAdd(c *check.C) {
+	api := dashboardStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	d := newDashboard(client, "", 10)
+	d.refresh()
+	d.handleKey("down")
+	api.reset()
+	d.handleKey("+")
+	d.handleKey("y")
+	calls := api.calls()
+	c.Assert(calls[0], check.Equals, "PUT /apps/app2/units")
+	c.Assert(d.status, check.Equals, "---> Unit added")
+	c.Assert(d.selected, check.Equals, "app2")
+}
+
+func (s *S) TestDashboardCancelAction(c *check.C) {
+	api := dashboardStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	d := newDashboard(client, "", 10)
+	d.refresh()
+	api.reset()
+	d.handleKey("r")
+	d.handleKey("n")
+	c.Assert(api.calls(), check.HasLen, 0)
+	c.Assert(d.status, check.Equals, "Cancelled.")
+}
+
+func (s *S) TestReadKeys(c *check.C) {
+	keys := make(chan string)
+	go readKeys(bytes.NewBufferString("j\033[A\033[Br+y\x03"), keys)
+	var got []string
+	for key := range keys {
+		got = append(got, key)
+	}
+	c.Assert(got, check.DeepEquals, []string{"j", "up", "down", "r", "+", "y", "ctrl-c"})
+}
+
+func (s *S) TestUnitsPerProcess(c *check.C) {
+	units := []unit{
+		{ID: "app1/0", Status: "started", ProcessName: "worker"},
+		{ID: "app1/1", Status: "error", ProcessName: "web"},
+		{ID: "app1/2", Status: "started", ProcessName: "web"},
+		{Status: "started", ProcessName: "web"},
+	}
+	c.Assert(unitsPerProcess(units), check.Equals, "web: 1/2, worker: 1/1")
+	c.Assert(unitsPerProcess([]unit{{ID: "app1/0", Status: "started"}}), check.Equals, "1/1")
+}
@@ -14,6 +14,7 @@ import (
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path"
 	"sort"
@@ -117,6 +118,8 @@ func (c *appDeployList) Run(context *cmd.Context, client *cmd.Client) error {
 
 type appDeploy struct {
 	cmd.GuessingCommand
+	lockWaiter
+	fs *gnuflag.FlagSet
 }
 
 func (c *appDeploy) Info() *cmd.Info {
@@ -131,7 +134,7 @@ calls are:
 `
 	return &cmd.Info{
 		Name:    "app-deploy",
-		Usage:   "app-deploy [-a/--app <appname>] <file-or-dir-1> [file-or-dir-2] ... [file-or-dir-n]",
+		Usage:   "app-deploy [-a/--app <appname>] [--wait-lock 5m] <file-or-dir-1> [file-or-dir-2] ... [file-or-dir-n]",
 		Desc:    desc,
 		MinArgs: 1,
 	}
@@ -143,6 +146,10 @@ func (c *appDeploy) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
 	url, err := cmd.GetURL("/apps/" + appName)
 	if err != nil {
 		return err
@@ -200,6 +207,13 @@ func (c *appDeploy) Run(context *cmd.Context, client *cmd.Client) error {
 	return cmd.ErrAbortCommand
 }
 
+func (c *appDeploy) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
+	}
+	return c.fs
+}
+
 func targz(ctx *cmd.Context, destination io.Writer, filepaths ...string) error {
 	var buf bytes.Buffer
 	tarWriter := tar.NewWriter(&buf)
@@ -385,11 +399,22 @@ func (c *appDeployRollback) Run(context *cmd.Context, client *cmd.Client) error
 	if !c.Confirm(context, fmt.Sprintf("Are you sure you want to rollback app %q to image %q?", appName, imgName)) {
 		return nil
 	}
-	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/deploy/rollback", appName))
+	return deployImage(context.Stdout, client, appName, imgName, true)
+}
+
+// deployImage deploys an existing image to the app, streaming the output of
+// the API to out. When rollback is true, the deploy is made through the
+// rollback endpoint, which only accepts images of previous deploys of the app.
+func deployImage(out io.Writer, client *cmd.Client, appName, image string, rollback bool) error {
+	path := fmt.Sprintf("/apps/%s/deploy", appName)
+	if rollback {
+		path += "/rollback"
+	}
+	url, err := cmd.GetURL(path)
 	if err != nil {
 		return err
 	}
-	body := strings.NewReader("image=" + imgName)
+	body := strings.NewReader(neturl.Values{"image": {image}}.Encode())
 	request, err := http.NewRequest("POST", url, body)
 	if err != nil {
 		return err
@@ -400,7 +425,7 @@ func (c *appDeployRollback) Run(context *cmd.Context, client *cmd.Client) error
 		return err
 	}
 	defer response.Body.Close()
-	w := tsuruIo.NewStreamWriter(context.Stdout, nil)
+	w := tsuruIo.NewStreamWriter(out, nil)
 	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, response.Body) {
 	}
 	if err != nil {
@@ -412,3 +437,45 @@ func (c *appDeployRollback) Run(context *cmd.Context, client *cmd.Client) error
 	}
 	return nil
 }
+
+// currentImage returns the image of the latest successful deploy of the app,
+// or an empty string if the app was never deployed.
+func currentImage(client *cmd.Client, appName string) (string, error) {
+	deploys, err := lastDeploys(client, appName, 10)
+	if err != nil {
+		return "", err
+	}
+	for _, deploy := range deploys {
+		if deploy.Error == "" && deploy.Image != "" {
+			return deploy.Image, nil
+		}
+	}
+	return "", nil
+}
+
+// lastDeploys returns up to limit deploys of the app, newest first.
+func lastDeploys(client *cmd.Client, appName string, limit int) ([]tsuruapp.DeployData, error) {
+	url, err := cmd.GetURL(fmt.Sprintf("/deploys?app=%s&limit=%d", appName, limit))
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	var deploys []tsuruapp.DeployData
+	err = json.NewDecoder(response.Body).Decode(&deploys)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(deployList(deploys)))
+	return deploys, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var dotEnvName = regexp.MustCompile(`^\w+$`)
+
+// parseDotEnv reads environment variables written in dotenv syntax. Blank
+// lines and lines starting with # are ignored, and declarations may be
+// prefixed with "export". Values may be unquoted, single quoted (taken
+// literally) or double quoted, in which case escape sequences are expanded
+// and the value may span multiple lines.
+func parseDotEnv(r io.Reader) (map[string]string, error) {
+	variables := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	var lineNumber int
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimLeft(line[len("export"):], " \t")
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected NAME=value", lineNumber)
+		}
+		name := strings.TrimSpace(parts[0])
+		if !dotEnvName.MatchString(name) {
+			return nil, fmt.Errorf("line %d: invalid variable name %q", lineNumber, name)
+		}
+		raw := strings.TrimLeft(parts[1], " \t")
+		var value, rest string
+		switch {
+		case strings.HasPrefix(raw, `"`):
+			start := lineNumber
+			var closed bool
+			value, rest, closed = unquoteDotEnv(raw[1:])
+			for !closed {
+				if !scanner.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated double-quoted value for %s", start, name)
+				}
+				lineNumber++
+				raw += "\n" + scanner.Text()
+				value, rest, closed = unquoteDotEnv(raw[1:])
+			}
+		case strings.HasPrefix(raw, "'"):
+			end := strings.Index(raw[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single-quoted value for %s", lineNumber, name)
+			}
+			value, rest = raw[1:end+1], raw[end+2:]
+		default:
+			value = raw
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			}
+			if i := strings.Index(value, "\t#"); i >= 0 {
+				value = value[:i]
+			}
+			value = strings.TrimSpace(value)
+		}
+		rest = strings.TrimSpace(rest)
+		if rest != "" && rest[0] != '#' {
+			return nil, fmt.Errorf("line %d: unexpected characters after the value of %s", lineNumber, name)
+		}
+		variables[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// quoteDotEnv returns value as a double-quoted dotenv value, escaping the
+// characters that parseDotEnv would otherwise interpret.
+func quoteDotEnv(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		`$`, `\$`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+	)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// unquoteDotEnv expands the escape sequences of a double-quoted value, up to
+// the closing quote. It returns the expanded value, whatever comes after the
+// closing quote and whether the closing quote was found at all.
+func unquoteDotEnv(s string) (string, string, bool) {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return buf.String(), s[i+1:], true
+		case '\\':
+			if i+1 == len(s) {
+				buf.WriteByte(s[i])
+				continue
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case '"', '\\', '$':
+				buf.WriteByte(s[i])
+			default:
+				buf.WriteByte('\\')
+				buf.WriteByte(s[i])
+			}
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String(), "", false
+}
@@ -0,0 +1,66 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestParseDotEnv(c *check.C) {
+	content := `# database settings
+DATABASE_HOST=somehost
+export DATABASE_USER=root
+  DATABASE_PORT = 5432
+
+INLINE_COMMENT=value # ignored
+HASH_IN_VALUE=abc#def
+EMPTY=
+SINGLE='literal $HOME \n # kept'
+DOUBLE="tab\there \"quoted\" \\ \$HOME"
+MULTILINE="first line
+second line"   # trailing comment
+`
+	variables, err := parseDotEnv(strings.NewReader(content))
+	c.Assert(err, check.IsNil)
+	c.Assert(variables, check.DeepEquals, map[string]string{
+		"DATABASE_HOST":  "somehost",
+		"DATABASE_USER":  "root",
+		"DATABASE_PORT":  "5432",
+		"INLINE_COMMENT": "value",
+		"HASH_IN_VALUE":  "abc#def",
+		"EMPTY":          "",
+		"SINGLE":         `literal $HOME \n # kept`,
+		"DOUBLE":         "tab\there \"quoted\" \\ $HOME",
+		"MULTILINE":      "first line\nsecond line",
+	})
+}
+
+func (s *S) TestParseDotEnvLastDeclarationWins(c *check.C) {
+	variables, err := parseDotEnv(strings.NewReader("A=1\nA=2\n"))
+	c.Assert(err, check.IsNil)
+	c.Assert(variables, check.DeepEquals, map[string]string{"A": "2"})
+}
+
+func (s *S) TestParseDotEnvErrors(c *check.C) {
+	var tests = []struct {
+		content string
+		err     string
+	}{
+		{"A=1\nNOVALUE\n", "line 2: expected NAME=value"},
+		{"MY-VAR=1", `line 1: invalid variable name "MY-VAR"`},
+		{"A=1\nB=\"open\nstill open\n", "line 2: unterminated double-quoted value for B"},
+		{"A='open", "line 1: unterminated single-quoted value for A"},
+		{`A="closed" garbage`, "line 1: unexpected characters after the value of A"},
+	}
+	for _, t := range tests {
+		_, err := parseDotEnv(strings.NewReader(t.content))
+		c.Check(err, check.NotNil)
+		if err != nil {
+			c.Check(err.Error(), check.Equals, t.err)
+		}
+	}
+}
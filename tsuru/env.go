@@ -12,6 +12,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
 	"regexp"
 	"sort"
 	"strings"
@@ -33,54 +34,146 @@ Example:
 
   tsuru env-set NAME=value OTHER_NAME="value with spaces" ANOTHER_NAME='using single quotes' -p`
 
+const privateEnvValue = "*** (private variable)"
+
+type envVar struct {
+	Name   string `json:"name" yaml:"name"`
+	Value  string `json:"value" yaml:"value"`
+	Public bool   `json:"public" yaml:"public"`
+}
+
+type envVarList []envVar
+
+func (l envVarList) Len() int {
+	return len(l)
+}
+
+func (l envVarList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+func (l envVarList) Less(i, j int) bool {
+	return l[i].Name < l[j].Name
+}
+
 type envGet struct {
 	cmd.GuessingCommand
+	fs     *gnuflag.FlagSet
+	format string
 }
 
 func (c *envGet) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "env-get",
-		Usage:   "env-get [-a/--app appname] [ENVIRONMENT_VARIABLE1] [ENVIRONMENT_VARIABLE2] ...",
-		Desc:    `Retrieves environment variables for an application.`,
+		Name:  "env-get",
+		Usage: "env-get [-a/--app appname] [--format dotenv|json|yaml|shell] [ENVIRONMENT_VARIABLE1] [ENVIRONMENT_VARIABLE2] ...",
+		Desc: `Retrieves environment variables for an application.
+
+The [[--format]] flag changes how variables are displayed. The "dotenv" and
+"shell" formats quote values so the output can be used with [[tsuru env-set
+--file]] or sourced by a shell, private variables are listed as comments. The
+"json" and "yaml" formats include the "public" flag of each variable, the
+values of private variables are masked.`,
 		MinArgs: 0,
 	}
 }
 
 func (c *envGet) Run(context *cmd.Context, client *cmd.Client) error {
+	err := checkFormat(c.format, "dotenv", "json", "yaml", "shell")
+	if err != nil {
+		return err
+	}
 	b, err := requestEnvURL("GET", c.GuessingCommand, context.Args, client)
 	if err != nil {
 		return err
 	}
-	var variables []map[string]interface{}
+	var variables []envVar
 	err = json.Unmarshal(b, &variables)
 	if err != nil {
 		return err
 	}
+	sort.Sort(envVarList(variables))
+	for i := range variables {
+		if !variables[i].Public {
+			variables[i].Value = privateEnvValue
+		}
+	}
+	switch c.format {
+	case "json", "yaml":
+		return renderStructured(context.Stdout, c.format, variables)
+	case "dotenv", "shell":
+		for _, v := range variables {
+			if !v.Public {
+				fmt.Fprintf(context.Stdout, "# %s=%s\n", v.Name, v.Value)
+			} else if c.format == "shell" {
+				fmt.Fprintf(context.Stdout, "export %s=%s\n", v.Name, shellQuote(v.Value))
+			} else {
+				fmt.Fprintf(context.Stdout, "%s=%s\n", v.Name, quoteDotEnv(v.Value))
+			}
+		}
+		return nil
+	}
 	formatted := make([]string, 0, len(variables))
 	for _, v := range variables {
-		value := "*** (private variable)"
-		if v["public"].(bool) {
-			value = v["value"].(string)
-		}
-		formatted = append(formatted, fmt.Sprintf("%s=%s", v["name"], value))
+		formatted = append(formatted, fmt.Sprintf("%s=%s", v.Name, v.Value))
 	}
-	sort.Strings(formatted)
 	fmt.Fprintln(context.Stdout, strings.Join(formatted, "\n"))
 	return nil
 }
 
+func (c *envGet) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = c.GuessingCommand.Flags()
+		c.fs.StringVar(&c.format, "format", "", "Output format: dotenv, json, yaml or shell")
+	}
+	return c.fs
+}
+
+// shellQuote wraps value in single quotes, so a POSIX shell reads it
+// literally.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
 type envSet struct {
 	cmd.GuessingCommand
+	lockWaiter
 	fs      *gnuflag.FlagSet
 	private bool
+	file    string
 }
 
 func (c *envSet) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "env-set",
-		Usage:   "env-set <NAME=value> [NAME=value] ... [-a/--app appname] [-p/--private]",
-		Desc:    `Sets environment variables for an application.`,
-		MinArgs: 1,
+		Name:  "env-set",
+		Usage: "env-set <NAME=value> [NAME=value] ... [-a/--app appname] [-p/--private] [-f/--file path] [--wait-lock 5m]",
+		Desc: `Sets environment variables for an application.
+
+The [[--file]] flag reads variables from a file in dotenv format, use "-" to
+read them from the standard input. Lines starting with # are ignored,
+declarations may be prefixed with "export", and values may be quoted. Double
+quoted values may contain escape sequences and span multiple lines.
+Variables given in the command line take precedence over the ones in the file.
+
+The [[--private]] flag applies to all variables being set, including the ones
+read from the file.
+
+Values can also reference local secrets, which are resolved by the client
+and always set as private, so they don't end up in the shell history:
+
+::
+
+    $ tsuru env-set DB_PASS=@file:./secret.txt API_KEY=@env:LOCAL_VAR TOKEN="@cmd:pass show x"
+
+"@file:" reads the value from a file, "@env:" from a local environment
+variable and "@cmd:" from the output of a command, which is split on
+whitespace and not run by a shell. A single trailing newline is removed. Use
+"@@" to set a value that starts with a literal "@". References are only
+resolved in the command line, values read with [[--file]] are always taken
+literally.
+
+Private and public variables are set in separate requests, so setting
+references together with public variables restarts the app twice.`,
+		MinArgs: 0,
 	}
 }
 
@@ -90,64 +183,102 @@ func (c *envSet) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	raw := strings.Join(context.Args, "\n")
-	regex := regexp.MustCompile(`(\w+=[^\n]+)(\n|$)`)
-	decls := regex.FindAllStringSubmatch(raw, -1)
-	if len(decls) < 1 || len(decls) != len(context.Args) {
-		return errors.New(envSetValidationMessage)
-	}
-	variables := make(map[string]string, len(decls))
-	for _, v := range decls {
-		parts := strings.SplitN(v[1], "=", 2)
-		variables[parts[0]] = parts[1]
-	}
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(variables)
-	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/env", appName))
+	err = c.waitLock(context.Stdout, client, appName)
 	if err != nil {
 		return err
 	}
-	if c.private {
-		url += "?private=1"
-	}
-	request, err := http.NewRequest("POST", url, &buf)
-	if err != nil {
-		return err
+	variables := make(map[string]string)
+	if c.file != "" {
+		variables, err = readEnvFile(context, c.file)
+		if err != nil {
+			return err
+		}
+		if len(variables) == 0 && len(context.Args) == 0 {
+			return fmt.Errorf("no environment variables found in %q", c.file)
+		}
 	}
-	response, err := client.Do(request)
-	if err != nil {
-		return err
+	var secrets map[string]string
+	if len(context.Args) > 0 || c.file == "" {
+		raw := strings.Join(context.Args, "\n")
+		regex := regexp.MustCompile(`(\w+=[^\n]+)(\n|$)`)
+		decls := regex.FindAllStringSubmatch(raw, -1)
+		if len(decls) < 1 || len(decls) != len(context.Args) {
+			return errors.New(envSetValidationMessage)
+		}
+		args := make(map[string]string, len(decls))
+		for _, v := range decls {
+			parts := strings.SplitN(v[1], "=", 2)
+			args[parts[0]] = parts[1]
+		}
+		// References are resolved only in the command line, values read
+		// from files are always taken literally.
+		secrets, err = resolveEnvRefs(context, args)
+		if err != nil {
+			return err
+		}
+		for name, value := range args {
+			variables[name] = value
+		}
+		for name := range secrets {
+			delete(variables, name)
+		}
 	}
-	w := tsuruIo.NewStreamWriter(context.Stdout, nil)
-	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, response.Body) {
+	if c.file != "" && len(variables) > 0 {
+		kind := "variable(s)"
+		if c.private {
+			kind = "private variable(s)"
+		}
+		names := sortedEnvNames(variables)
+		fmt.Fprintf(context.Stdout, "Setting %d %s in app %q: %s\n", len(names), kind, appName, strings.Join(names, ", "))
 	}
-	if err != nil {
-		return err
+	if len(secrets) > 0 {
+		names := sortedEnvNames(secrets)
+		fmt.Fprintf(context.Stdout, "Setting %d variable(s) resolved locally as private in app %q: %s\n", len(names), appName, strings.Join(names, ", "))
+		if c.private || len(variables) == 0 {
+			for name, value := range variables {
+				secrets[name] = value
+			}
+			return setEnvVars(context.Stdout, client, appName, secrets, true)
+		}
+		err = setEnvVars(context.Stdout, client, appName, secrets, true)
+		if err != nil {
+			return err
+		}
 	}
-	unparsed := w.Remaining()
-	if len(unparsed) > 0 {
-		return fmt.Errorf("unparsed message error: %s", string(unparsed))
+	return setEnvVars(context.Stdout, client, appName, variables, c.private)
+}
+
+func sortedEnvNames(variables map[string]string) []string {
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
 	}
-	return nil
+	sort.Strings(names)
+	return names
 }
 
 func (c *envSet) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
-		c.fs = c.GuessingCommand.Flags()
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
 		c.fs.BoolVar(&c.private, "private", false, "Private environment variables")
 		c.fs.BoolVar(&c.private, "p", false, "Private environment variables")
+		fileMessage := "Read environment variables from a dotenv file (use - for stdin)"
+		c.fs.StringVar(&c.file, "file", "", fileMessage)
+		c.fs.StringVar(&c.file, "f", "", fileMessage)
 	}
 	return c.fs
 }
 
 type envUnset struct {
 	cmd.GuessingCommand
+	lockWaiter
+	fs *gnuflag.FlagSet
 }
 
 func (c *envUnset) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:    "env-unset",
-		Usage:   "env-unset <ENVIRONMENT_VARIABLE1> [ENVIRONMENT_VARIABLE2] ... [ENVIRONMENT_VARIABLEN] [-a/--app appname]",
+		Usage:   "env-unset <ENVIRONMENT_VARIABLE1> [ENVIRONMENT_VARIABLE2] ... [ENVIRONMENT_VARIABLEN] [-a/--app appname] [--wait-lock 5m]",
 		Desc:    `Unset environment variables for an application.`,
 		MinArgs: 1,
 	}
@@ -159,38 +290,442 @@ func (c *envUnset) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/env", appName))
+	err = c.waitLock(context.Stdout, client, appName)
 	if err != nil {
 		return err
 	}
-	var buf bytes.Buffer
-	json.NewEncoder(&buf).Encode(context.Args)
-	request, err := http.NewRequest("DELETE", url, &buf)
+	return unsetEnvVars(context.Stdout, client, appName, context.Args)
+}
+
+func (c *envUnset) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
+	}
+	return c.fs
+}
+
+type envDiff struct{}
+
+func (c *envDiff) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "env-diff",
+		Usage: "env-diff <app1> <app2>",
+		Desc: `Compares the environment variables of two applications.
+
+Variables that exist only in <app2> are marked with "+", variables that exist
+only in <app1> are marked with "-" and variables whose values differ are marked
+with "~". Values of private variables can't be retrieved, so private variables
+are only reported when they exist in just one of the apps.`,
+		MinArgs: 2,
+		MaxArgs: 2,
+	}
+}
+
+func (c *envDiff) Run(context *cmd.Context, client *cmd.Client) error {
+	app1, app2 := context.Args[0], context.Args[1]
+	vars1, err := getEnvVars(app1, client)
 	if err != nil {
 		return err
 	}
-	response, err := client.Do(request)
+	vars2, err := getEnvVars(app2, client)
+	if err != nil {
+		return err
+	}
+	diff := diffEnvVars(vars1, vars2)
+	if len(diff) == 0 {
+		fmt.Fprintf(context.Stdout, "Apps %q and %q have the same environment variables.\n", app1, app2)
+		return nil
+	}
+	fmt.Fprintf(context.Stdout, "--- %s\n+++ %s\n", app1, app2)
+	for _, line := range diff {
+		fmt.Fprintln(context.Stdout, line)
+	}
+	return nil
+}
+
+func formatEnvVar(v envVar) string {
+	if !v.Public {
+		return v.Name + " (private)"
+	}
+	return fmt.Sprintf("%s=%q", v.Name, v.Value)
+}
+
+func formatEnvValue(v envVar) string {
+	if !v.Public {
+		return "(private)"
+	}
+	return fmt.Sprintf("%q", v.Value)
+}
+
+// diffEnvVars returns one line for each variable that was added, removed or
+// changed from vars1 to vars2, sorted by variable name.
+func diffEnvVars(vars1, vars2 []envVar) []string {
+	byName := make(map[string]envVar, len(vars2))
+	for _, v := range vars2 {
+		byName[v.Name] = v
+	}
+	changes := make(map[string]string)
+	for _, v1 := range vars1 {
+		v2, ok := byName[v1.Name]
+		delete(byName, v1.Name)
+		switch {
+		case !ok:
+			changes[v1.Name] = "- " + formatEnvVar(v1)
+		case !v1.Public && !v2.Public:
+		case v1.Public != v2.Public || v1.Value != v2.Value:
+			changes[v1.Name] = fmt.Sprintf("~ %s: %s -> %s", v1.Name, formatEnvValue(v1), formatEnvValue(v2))
+		}
+	}
+	for _, v2 := range byName {
+		changes[v2.Name] = "+ " + formatEnvVar(v2)
+	}
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	diff := make([]string, len(names))
+	for i, name := range names {
+		diff[i] = changes[name]
+	}
+	return diff
+}
+
+type envCopy struct {
+	cmd.ConfirmationCommand
+	lockWaiter
+	fs   *gnuflag.FlagSet
+	from string
+	to   string
+}
+
+func (c *envCopy) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "env-copy",
+		Usage: "env-copy --from <app> --to <app> [ENVIRONMENT_VARIABLE1] [ENVIRONMENT_VARIABLE2] ... [-y/--assume-yes] [--wait-lock 5m]",
+		Desc: `Copies public environment variables from one application to another.
+
+If no variable names are given, all public variables of the source app are
+copied. Private variables can't be retrieved, so they are never copied.
+Before overwriting variables that already exist in the destination app with a
+different value, the command asks for confirmation. Variables that are private
+in the destination app stay private, and are set in a separate request.`,
+		MinArgs: 0,
+	}
+}
+
+func (c *envCopy) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	if c.from == "" || c.to == "" {
+		return errors.New("Please use the --from and --to flags to specify the source and destination apps.")
+	}
+	source, err := getEnvVars(c.from, client)
 	if err != nil {
 		return err
 	}
-	w := tsuruIo.NewStreamWriter(context.Stdout, nil)
-	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, response.Body) {
+	if len(context.Args) > 0 {
+		byName := make(map[string]envVar, len(source))
+		for _, v := range source {
+			byName[v.Name] = v
+		}
+		selected := make([]envVar, 0, len(context.Args))
+		for _, name := range context.Args {
+			v, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("variable %q is not set in app %q", name, c.from)
+			}
+			selected = append(selected, v)
+		}
+		source = selected
 	}
+	variables := make(map[string]string, len(source))
+	var skipped []string
+	for _, v := range source {
+		if v.Public {
+			variables[v.Name] = v.Value
+		} else {
+			skipped = append(skipped, v.Name)
+		}
+	}
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		fmt.Fprintf(context.Stdout, "Skipping private variable(s) of app %q: %s\n", c.from, strings.Join(skipped, ", "))
+	}
+	if len(variables) == 0 {
+		fmt.Fprintf(context.Stdout, "No public variables to copy from app %q.\n", c.from)
+		return nil
+	}
+	destination, err := getEnvVars(c.to, client)
 	if err != nil {
 		return err
 	}
-	unparsed := w.Remaining()
-	if len(unparsed) > 0 {
-		return fmt.Errorf("unparsed message error: %s", string(unparsed))
+	var overwritten []string
+	private := make(map[string]string)
+	for _, v := range destination {
+		value, ok := variables[v.Name]
+		if !ok {
+			continue
+		}
+		if v.Public && v.Value == value {
+			delete(variables, v.Name)
+		} else if v.Public {
+			overwritten = append(overwritten, v.Name)
+		} else {
+			overwritten = append(overwritten, v.Name+" (private)")
+			private[v.Name] = value
+			delete(variables, v.Name)
+		}
+	}
+	if len(variables) == 0 && len(private) == 0 {
+		fmt.Fprintf(context.Stdout, "All variables already have the same values in app %q.\n", c.to)
+		return nil
+	}
+	if len(overwritten) > 0 {
+		sort.Strings(overwritten)
+		question := fmt.Sprintf("The following variables will be overwritten in app %q: %s. Continue?", c.to, strings.Join(overwritten, ", "))
+		if !c.Confirm(context, question) {
+			return nil
+		}
+	}
+	err = c.waitLock(context.Stdout, client, c.to)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(variables)+len(private))
+	for name := range variables {
+		names = append(names, name)
+	}
+	for name := range private {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(context.Stdout, "Copying %d variable(s) from app %q to app %q: %s\n", len(names), c.from, c.to, strings.Join(names, ", "))
+	if len(variables) > 0 {
+		err = setEnvVars(context.Stdout, client, c.to, variables, false)
+		if err != nil {
+			return err
+		}
+	}
+	if len(private) > 0 {
+		return setEnvVars(context.Stdout, client, c.to, private, true)
+	}
+	return nil
+}
+
+func (c *envCopy) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(c.ConfirmationCommand.Flags(), c.lockWaiter.Flags())
+		c.fs.StringVar(&c.from, "from", "", "The app to copy variables from")
+		c.fs.StringVar(&c.to, "to", "", "The app to copy variables to")
+	}
+	return c.fs
+}
+
+type envSync struct {
+	cmd.GuessingCommand
+	cmd.ConfirmationCommand
+	lockWaiter
+	fs      *gnuflag.FlagSet
+	file    string
+	private bool
+	prune   bool
+	// yes is set by -y/--assume-yes, which env-sync registers itself
+	// because reading from the standard input requires it.
+	yes bool
+}
+
+func (c *envSync) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "env-sync",
+		Usage: "env-sync -f/--file <path> [-a/--app appname] [-p/--private] [--prune] [-y/--assume-yes] [--wait-lock 5m]",
+		Desc: `Makes the environment variables of an application match a file in dotenv
+format (see [[tsuru env-set]]), use "-" to read it from the standard input.
+
+The command compares the file with the current variables of the app, prints
+the variables that will be set and unset, and applies all changes at once
+after confirmation.
+
+Variables are set as public unless the [[--private]] flag is used. Values of
+private variables can't be retrieved, so a public sync never touches
+variables that are private in the app, while a private sync always sets all
+variables in the file.
+
+Variables missing from the file are kept, unless the [[--prune]] flag is
+used, in which case public variables that are not in the file are unset.
+Private variables are never unset by env-sync.
+
+When the file is read from the standard input, the changes can't be
+confirmed interactively, so [[--assume-yes]] is required.`,
+		MinArgs: 0,
+	}
+}
+
+func (c *envSync) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	if c.file == "" {
+		return errors.New("Please use the -f/--file flag to specify the file with the environment variables.")
+	}
+	if c.file == "-" && !c.yes {
+		return errors.New("the changes can't be confirmed when the file is read from the standard input, use -y/--assume-yes to apply them")
+	}
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	desired, err := readEnvFile(context, c.file)
+	if err != nil {
+		return err
+	}
+	current, err := getEnvVars(appName, client)
+	if err != nil {
+		return err
+	}
+	plan := planEnvSync(current, desired, c.private, c.prune)
+	for _, name := range plan.kept {
+		fmt.Fprintf(context.Stdout, "  %s is private in the app and will be left unchanged (use --private to update it)\n", name)
+	}
+	if len(plan.set) == 0 && len(plan.unset) == 0 {
+		fmt.Fprintf(context.Stdout, "App %q is already in sync with %q.\n", appName, c.file)
+		return nil
+	}
+	question := fmt.Sprintf("Apply these changes to app %q?", appName)
+	if len(plan.uncompared) == len(plan.set) && len(plan.unset) == 0 {
+		fmt.Fprintf(context.Stdout, "App %q is in sync with %q, except for %d private variable(s) whose current values can't be compared: %s\n",
+			appName, c.file, len(plan.uncompared), strings.Join(plan.uncompared, ", "))
+		question = "Set them again?"
+	} else {
+		fmt.Fprintf(context.Stdout, "Changes to environment variables of app %q:\n", appName)
+		for _, line := range plan.lines {
+			fmt.Fprintln(context.Stdout, line)
+		}
+	}
+	if !c.yes && !c.Confirm(context, question) {
+		return nil
+	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
+	if len(plan.set) > 0 {
+		err = setEnvVars(context.Stdout, client, appName, plan.set, c.private)
+		if err != nil {
+			return err
+		}
+	}
+	if len(plan.unset) > 0 {
+		return unsetEnvVars(context.Stdout, client, appName, plan.unset)
 	}
 	return nil
 }
 
+func (c *envSync) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
+		c.fs.BoolVar(&c.yes, "assume-yes", false, "Don't ask for confirmation.")
+		c.fs.BoolVar(&c.yes, "y", false, "Don't ask for confirmation.")
+		fileMessage := "The dotenv file with the expected environment variables (use - for stdin)"
+		c.fs.StringVar(&c.file, "file", "", fileMessage)
+		c.fs.StringVar(&c.file, "f", "", fileMessage)
+		c.fs.BoolVar(&c.private, "private", false, "Set the variables in the file as private")
+		c.fs.BoolVar(&c.private, "p", false, "Set the variables in the file as private")
+		c.fs.BoolVar(&c.prune, "prune", false, "Unset public variables that are not in the file")
+	}
+	return c.fs
+}
+
+type envSyncPlan struct {
+	set        map[string]string
+	unset      []string
+	kept       []string
+	uncompared []string
+	lines      []string
+}
+
+// planEnvSync computes the operations needed to make the current variables
+// of an app match the desired ones.
+func planEnvSync(current []envVar, desired map[string]string, private, prune bool) envSyncPlan {
+	plan := envSyncPlan{set: make(map[string]string)}
+	changes := make(map[string]string)
+	byName := make(map[string]envVar, len(current))
+	for _, v := range current {
+		byName[v.Name] = v
+	}
+	for name, value := range desired {
+		v, ok := byName[name]
+		switch {
+		case !ok && private:
+			changes[name] = fmt.Sprintf("+ %s (private)", name)
+		case !ok:
+			changes[name] = "+ " + formatEnvVar(envVar{Name: name, Value: value, Public: true})
+		case private && v.Public:
+			changes[name] = fmt.Sprintf("~ %s: %s -> (private)", name, formatEnvValue(v))
+		case private:
+			changes[name] = fmt.Sprintf("~ %s (private, current value can't be compared)", name)
+			plan.uncompared = append(plan.uncompared, name)
+		case !v.Public:
+			plan.kept = append(plan.kept, name)
+			continue
+		case v.Value == value:
+			continue
+		default:
+			changes[name] = fmt.Sprintf("~ %s: %s -> %q", name, formatEnvValue(v), value)
+		}
+		plan.set[name] = value
+	}
+	if prune {
+		for _, v := range current {
+			if _, ok := desired[v.Name]; !ok && v.Public {
+				changes[v.Name] = "- " + formatEnvVar(v)
+				plan.unset = append(plan.unset, v.Name)
+			}
+		}
+	}
+	names := make([]string, 0, len(changes))
+	for name := range changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		plan.lines = append(plan.lines, changes[name])
+	}
+	sort.Strings(plan.unset)
+	sort.Strings(plan.kept)
+	sort.Strings(plan.uncompared)
+	return plan
+}
+
+// readEnvFile reads environment variables from the given dotenv file, or
+// from the standard input when path is "-".
+func readEnvFile(context *cmd.Context, path string) (map[string]string, error) {
+	var input io.Reader
+	if path == "-" {
+		input = context.Stdin
+	} else {
+		f, err := filesystem().Open(path)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("file %q doesn't exist", path)
+		} else if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		input = f
+	}
+	variables, err := parseDotEnv(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid environment file %q: %s", path, err)
+	}
+	return variables, nil
+}
+
 func requestEnvURL(method string, g cmd.GuessingCommand, args []string, client *cmd.Client) ([]byte, error) {
 	appName, err := g.Guess()
 	if err != nil {
 		return nil, err
 	}
+	return requestAppEnvURL(method, appName, args, client)
+}
+
+func requestAppEnvURL(method string, appName string, args []string, client *cmd.Client) ([]byte, error) {
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/env", appName))
 	if err != nil {
 		return nil, err
@@ -212,3 +747,65 @@ func requestEnvURL(method string, g cmd.GuessingCommand, args []string, client *
 	}
 	return b, nil
 }
+
+// getEnvVars returns all environment variables of the given app, sorted by
+// name.
+func getEnvVars(appName string, client *cmd.Client) ([]envVar, error) {
+	b, err := requestAppEnvURL("GET", appName, []string{}, client)
+	if err != nil {
+		return nil, err
+	}
+	var variables []envVar
+	err = json.Unmarshal(b, &variables)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(envVarList(variables))
+	return variables, nil
+}
+
+func setEnvVars(w io.Writer, client *cmd.Client, appName string, variables map[string]string, private bool) error {
+	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/env", appName))
+	if err != nil {
+		return err
+	}
+	if private {
+		url += "?private=1"
+	}
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(variables)
+	return streamEnvRequest(w, client, "POST", url, &buf)
+}
+
+func unsetEnvVars(w io.Writer, client *cmd.Client, appName string, names []string) error {
+	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/env", appName))
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(names)
+	return streamEnvRequest(w, client, "DELETE", url, &buf)
+}
+
+func streamEnvRequest(w io.Writer, client *cmd.Client, method, url string, body io.Reader) error {
+	request, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	stream := tsuruIo.NewStreamWriter(w, nil)
+	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(stream, response.Body) {
+	}
+	if err != nil {
+		return err
+	}
+	unparsed := stream.Remaining()
+	if len(unparsed) > 0 {
+		return fmt.Errorf("unparsed message error: %s", string(unparsed))
+	}
+	return nil
+}
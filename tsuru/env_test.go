@@ -9,9 +9,11 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
+	"github.com/tsuru/tsuru/fs/fstest"
 	"github.com/tsuru/tsuru/io"
 	"gopkg.in/check.v1"
 )
@@ -119,11 +121,110 @@ func (s *S) TestEnvGetWithoutTheFlag(c *check.C) {
 	}
 	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
 	fake := &cmdtest.FakeGuesser{Name: "seek"}
-	err := (&envGet{cmd.GuessingCommand{G: fake}}).Run(&context, client)
+	err := (&envGet{GuessingCommand: cmd.GuessingCommand{G: fake}}).Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(stdout.String(), check.Equals, result)
 }
 
+func (s *S) TestEnvGetFormatDotEnv(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	jsonResult := `[{"name": "DATABASE_USER", "value": "some\"user\"", "public": true}, {"name": "DATABASE_HOST", "value": "somehost", "public": false}, {"name": "CERT", "value": "line1\nline2 $HOME", "public": true}]`
+	result := `CERT="line1\nline2 \$HOME"
+# DATABASE_HOST=*** (private variable)
+DATABASE_USER="some\"user\""
+`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: jsonResult, Status: http.StatusOK}}, nil, manager)
+	command := envGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--format", "dotenv"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, result)
+	variables, err := parseDotEnv(&stdout)
+	c.Assert(err, check.IsNil)
+	c.Assert(variables, check.DeepEquals, map[string]string{
+		"CERT":          "line1\nline2 $HOME",
+		"DATABASE_USER": `some"user"`,
+	})
+}
+
+func (s *S) TestEnvGetFormatShell(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	jsonResult := `[{"name": "DATABASE_USER", "value": "it's me", "public": true}, {"name": "DATABASE_HOST", "value": "somehost", "public": false}]`
+	result := `# DATABASE_HOST=*** (private variable)
+export DATABASE_USER='it'\''s me'
+`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: jsonResult, Status: http.StatusOK}}, nil, manager)
+	command := envGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--format", "shell"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, result)
+}
+
+func (s *S) TestEnvGetFormatJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	jsonResult := `[{"name": "DATABASE_USER", "value": "someuser", "public": true}, {"name": "DATABASE_HOST", "value": "somehost", "public": false}]`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: jsonResult, Status: http.StatusOK}}, nil, manager)
+	command := envGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--format", "json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var variables []envVar
+	err = json.Unmarshal(stdout.Bytes(), &variables)
+	c.Assert(err, check.IsNil)
+	c.Assert(variables, check.DeepEquals, []envVar{
+		{Name: "DATABASE_HOST", Value: "*** (private variable)", Public: false},
+		{Name: "DATABASE_USER", Value: "someuser", Public: true},
+	})
+}
+
+func (s *S) TestEnvGetFormatYAML(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	jsonResult := `[{"name": "DATABASE_USER", "value": "someuser", "public": true}, {"name": "DATABASE_HOST", "value": "somehost", "public": true}]`
+	result := `- name: DATABASE_HOST
+  value: somehost
+  public: true
+- name: DATABASE_USER
+  value: someuser
+  public: true
+`
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: jsonResult, Status: http.StatusOK}}, nil, manager)
+	command := envGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--format", "yaml"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, result)
+}
+
+func (s *S) TestEnvGetInvalidFormat(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	command := envGet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--format", "xml"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `invalid format "xml", valid formats are: dotenv, json, yaml, shell`)
+}
+
 func (s *S) TestEnvSetInfo(c *check.C) {
 	c.Assert((&envSet{}).Info(), check.NotNil)
 }
@@ -358,7 +459,7 @@ func (s *S) TestEnvUnsetWithoutFlag(c *check.C) {
 	}
 	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
 	fake := &cmdtest.FakeGuesser{Name: "otherapp"}
-	err = (&envUnset{cmd.GuessingCommand{G: fake}}).Run(&context, client)
+	err = (&envUnset{GuessingCommand: cmd.GuessingCommand{G: fake}}).Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(stdout.String(), check.Equals, expectedOut)
 }
@@ -372,3 +473,699 @@ func (s *S) TestRequestEnvURL(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(b, check.DeepEquals, []byte(result))
 }
+
+func (s *S) TestEnvSetFromFile(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"DATABASE_USER=admin"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	rfs := fstest.RecordingFs{FileContent: "export DATABASE_HOST=somehost\nDATABASE_USER=root\nCERT=\"line1\nline2\"\n"}
+	fsystem = &rfs
+	defer func() {
+		fsystem = nil
+	}()
+	msg := io.SimpleJsonMessage{Message: "variable(s) successfully exported\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			want := map[string]string{
+				"DATABASE_HOST": "somehost",
+				"DATABASE_USER": "admin",
+				"CERT":          "line1\nline2",
+			}
+			defer req.Body.Close()
+			var got map[string]string
+			err := json.NewDecoder(req.Body).Decode(&got)
+			c.Assert(err, check.IsNil)
+			c.Assert(got, check.DeepEquals, want)
+			return req.URL.Path == "/apps/someapp/env" && req.Method == "POST" && req.URL.RawQuery == "private=1"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-p", "--file", "app.env"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(rfs.HasAction("open app.env"), check.Equals, true)
+	expected := `Setting 3 private variable(s) in app "someapp": CERT, DATABASE_HOST, DATABASE_USER` + "\n"
+	expected += "variable(s) successfully exported\n"
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvSetFileReferencesAreLiteral(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	rfs := fstest.RecordingFs{FileContent: "KEY=@file:/etc/passwd\nHANDLE=@@tsuru\n"}
+	fsystem = &rfs
+	defer func() {
+		fsystem = nil
+	}()
+	api := newStubAPI().on("POST /apps/someapp/env", http.StatusOK, `{"Message":"variable(s) successfully exported\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := envSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "--file", "app.env"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(rfs.HasAction("open /etc/passwd"), check.Equals, false)
+	var got map[string]string
+	err = json.Unmarshal([]byte(api.bodies[0]), &got)
+	c.Assert(err, check.IsNil)
+	c.Assert(got, check.DeepEquals, map[string]string{"KEY": "@file:/etc/passwd", "HANDLE": "@@tsuru"})
+}
+
+func (s *S) TestEnvSetWithReferences(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"DATABASE_HOST=somehost", "DATABASE_PASSWORD=@file:secret.txt"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	rfs := fstest.RecordingFs{FileContent: "s3cr3t\n"}
+	fsystem = &rfs
+	defer func() {
+		fsystem = nil
+	}()
+	msg := io.SimpleJsonMessage{Message: "variable(s) successfully exported\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	decodeBody := func(req *http.Request) map[string]string {
+		defer req.Body.Close()
+		var got map[string]string
+		err := json.NewDecoder(req.Body).Decode(&got)
+		c.Assert(err, check.IsNil)
+		return got
+	}
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					c.Assert(decodeBody(req), check.DeepEquals, map[string]string{"DATABASE_PASSWORD": "s3cr3t"})
+					return req.URL.Path == "/apps/someapp/env" && req.Method == "POST" && req.URL.RawQuery == "private=1"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					c.Assert(decodeBody(req), check.DeepEquals, map[string]string{"DATABASE_HOST": "somehost"})
+					return req.URL.Path == "/apps/someapp/env" && req.Method == "POST" && req.URL.RawQuery == ""
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Setting 1 variable(s) resolved locally as private in app "someapp": DATABASE_PASSWORD` + "\n"
+	expected += "variable(s) successfully exported\n"
+	expected += "variable(s) successfully exported\n"
+	c.Assert(stdout.String(), check.Equals, expected)
+	c.Assert(strings.Contains(stdout.String()+stderr.String(), "s3cr3t"), check.Equals, false)
+}
+
+func (s *S) TestEnvSetFromStdin(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("# comment\nDATABASE_HOST='some host'\n"),
+	}
+	msg := io.SimpleJsonMessage{Message: "variable(s) successfully exported\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			want := `{"DATABASE_HOST":"some host"}` + "\n"
+			defer req.Body.Close()
+			got, err := ioutil.ReadAll(req.Body)
+			c.Assert(err, check.IsNil)
+			return req.URL.Path == "/apps/someapp/env" && req.Method == "POST" && string(got) == want
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "-"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Setting 1 variable(s) in app "someapp": DATABASE_HOST` + "\n"
+	expected += "variable(s) successfully exported\n"
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvSetFromFileNotFound(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fsystem = &fstest.FileNotFoundFs{RecordingFs: fstest.RecordingFs{}}
+	defer func() {
+		fsystem = nil
+	}()
+	command := envSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "app.env"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `file "app.env" doesn't exist`)
+}
+
+func (s *S) TestEnvSetFromInvalidFile(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fsystem = &fstest.RecordingFs{FileContent: "DATABASE_HOST=somehost\nINVALID\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	command := envSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "app.env"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `invalid environment file "app.env": line 2: expected NAME=value`)
+}
+
+func (s *S) TestEnvSetFromEmptyFile(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fsystem = &fstest.RecordingFs{FileContent: "# nothing here\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	command := envSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "app.env"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `no environment variables found in "app.env"`)
+}
+
+func (s *S) TestEnvSetWithoutArgsAndFile(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	command := envSet{}
+	command.Flags().Parse(true, []string{"-a", "someapp"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, envSetValidationMessage)
+}
+
+func (s *S) TestEnvDiffInfo(c *check.C) {
+	c.Assert((&envDiff{}).Info(), check.NotNil)
+}
+
+func (s *S) TestEnvDiffRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	env1 := `[{"name": "SAME", "value": "1", "public": true}, {"name": "CHANGED", "value": "old", "public": true},
+{"name": "REMOVED", "value": "gone", "public": true}, {"name": "SECRET", "value": "*** (private variable)", "public": false},
+{"name": "PRIVATE_ONLY1", "value": "*** (private variable)", "public": false}, {"name": "NOW_PRIVATE", "value": "x", "public": true}]`
+	env2 := `[{"name": "SAME", "value": "1", "public": true}, {"name": "CHANGED", "value": "new value", "public": true},
+{"name": "ADDED", "value": "here", "public": true}, {"name": "SECRET", "value": "*** (private variable)", "public": false},
+{"name": "PRIVATE_ONLY2", "value": "*** (private variable)", "public": false}, {"name": "NOW_PRIVATE", "value": "*** (private variable)", "public": false}]`
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: env1, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/app1/env" && req.Method == "GET"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: env2, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/app2/env" && req.Method == "GET"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envDiff{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `--- app1
++++ app2
++ ADDED="here"
+~ CHANGED: "old" -> "new value"
+~ NOW_PRIVATE: "x" -> (private)
+- PRIVATE_ONLY1 (private)
++ PRIVATE_ONLY2 (private)
+- REMOVED="gone"
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvDiffRunWithoutDifferences(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	env := `[{"name": "SAME", "value": "1", "public": true}, {"name": "SECRET", "value": "*** (private variable)", "public": false}]`
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: env, Status: http.StatusOK}}, nil, manager)
+	command := envDiff{}
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, `Apps "app1" and "app2" have the same environment variables.`+"\n")
+}
+
+func (s *S) TestEnvCopyInfo(c *check.C) {
+	c.Assert((&envCopy{}).Info(), check.NotNil)
+}
+
+func (s *S) TestEnvCopyRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("y\n"),
+	}
+	source := `[{"name": "SAME", "value": "1", "public": true}, {"name": "CHANGED", "value": "new", "public": true},
+{"name": "ADDED", "value": "here", "public": true}, {"name": "SECRET", "value": "*** (private variable)", "public": false}]`
+	destination := `[{"name": "SAME", "value": "1", "public": true}, {"name": "CHANGED", "value": "old", "public": true}]`
+	msg := io.SimpleJsonMessage{Message: "variable(s) successfully exported\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: source, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/app1/env" && req.Method == "GET"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: destination, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/app2/env" && req.Method == "GET"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					var got map[string]string
+					err := json.NewDecoder(req.Body).Decode(&got)
+					c.Assert(err, check.IsNil)
+					c.Assert(got, check.DeepEquals, map[string]string{"ADDED": "here", "CHANGED": "new"})
+					return req.URL.Path == "/apps/app2/env" && req.Method == "POST" && req.URL.RawQuery == ""
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envCopy{}
+	command.Flags().Parse(true, []string{"--from", "app1", "--to", "app2"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Skipping private variable(s) of app "app1": SECRET
+The following variables will be overwritten in app "app2": CHANGED. Continue? (y/n) Copying 2 variable(s) from app "app1" to app "app2": ADDED, CHANGED
+variable(s) successfully exported
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvCopyRunWithoutConfirmation(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	source := `[{"name": "CHANGED", "value": "new", "public": true}]`
+	destination := `[{"name": "CHANGED", "value": "*** (private variable)", "public": false}]`
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: source, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/app1/env"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: destination, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/app2/env"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envCopy{}
+	command.Flags().Parse(true, []string{"--from", "app1", "--to", "app2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `The following variables will be overwritten in app "app2": CHANGED (private). Continue? (y/n) Abort.` + "\n"
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvCopyRunKeepsPrivateVariablesPrivate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().
+		on("GET /apps/app1/env", http.StatusOK, `[{"name":"TOKEN","value":"new","public":true},{"name":"DEBUG","value":"1","public":true}]`).
+		on("GET /apps/app2/env", http.StatusOK, `[{"name":"TOKEN","value":"*** (private variable)","public":false}]`).
+		on("POST /apps/app2/env", http.StatusOK, `{"Message":"variable(s) successfully exported\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := envCopy{}
+	command.Flags().Parse(true, []string{"--from", "app1", "--to", "app2", "-y"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/app1/env",
+		"GET /apps/app2/env",
+		"POST /apps/app2/env",
+		"POST /apps/app2/env?private=1",
+	})
+	c.Assert(strings.TrimSpace(api.bodies[2]), check.Equals, `{"DEBUG":"1"}`)
+	c.Assert(strings.TrimSpace(api.bodies[3]), check.Equals, `{"TOKEN":"new"}`)
+}
+
+func (s *S) TestEnvCopyRunSelectedVariables(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"ADDED"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	source := `[{"name": "CHANGED", "value": "new", "public": true}, {"name": "ADDED", "value": "here", "public": true}]`
+	destination := `[{"name": "CHANGED", "value": "old", "public": true}]`
+	msg := io.SimpleJsonMessage{Message: "variable(s) successfully exported\n"}
+	result, err := json.Marshal(msg)
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: source, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/app1/env"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: destination, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/app2/env"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					want := `{"ADDED":"here"}` + "\n"
+					got, err := ioutil.ReadAll(req.Body)
+					c.Assert(err, check.IsNil)
+					return req.URL.Path == "/apps/app2/env" && req.Method == "POST" && string(got) == want
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envCopy{}
+	command.Flags().Parse(true, []string{"--from", "app1", "--to", "app2"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Copying 1 variable(s) from app "app1" to app "app2": ADDED
+variable(s) successfully exported
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvCopyRunUnknownVariable(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"MISSING"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	source := `[{"name": "ADDED", "value": "here", "public": true}]`
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: source, Status: http.StatusOK}}, nil, manager)
+	command := envCopy{}
+	command.Flags().Parse(true, []string{"--from", "app1", "--to", "app2"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `variable "MISSING" is not set in app "app1"`)
+}
+
+func (s *S) TestEnvCopyRunWithoutApps(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	command := envCopy{}
+	command.Flags().Parse(true, []string{"--from", "app1"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "Please use the --from and --to flags to specify the source and destination apps.")
+}
+
+func (s *S) TestEnvSyncInfo(c *check.C) {
+	c.Assert((&envSync{}).Info(), check.NotNil)
+}
+
+func (s *S) TestEnvSyncRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("y\n"),
+	}
+	fsystem = &fstest.RecordingFs{FileContent: "SAME=1\nCHANGED=new\nADDED=here\nSECRET=value\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	current := `[{"name": "SAME", "value": "1", "public": true}, {"name": "CHANGED", "value": "old", "public": true},
+{"name": "REMOVED", "value": "gone", "public": true}, {"name": "SECRET", "value": "*** (private variable)", "public": false},
+{"name": "BOUND", "value": "*** (private variable)", "public": false}]`
+	setMsg, err := json.Marshal(io.SimpleJsonMessage{Message: "variable(s) successfully exported\n"})
+	c.Assert(err, check.IsNil)
+	unsetMsg, err := json.Marshal(io.SimpleJsonMessage{Message: "variable(s) successfully unset\n"})
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: current, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/someapp/env" && req.Method == "GET"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(setMsg), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					var got map[string]string
+					err := json.NewDecoder(req.Body).Decode(&got)
+					c.Assert(err, check.IsNil)
+					c.Assert(got, check.DeepEquals, map[string]string{"ADDED": "here", "CHANGED": "new"})
+					return req.URL.Path == "/apps/someapp/env" && req.Method == "POST" && req.URL.RawQuery == ""
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(unsetMsg), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					want := `["REMOVED"]` + "\n"
+					got, err := ioutil.ReadAll(req.Body)
+					c.Assert(err, check.IsNil)
+					return req.URL.Path == "/apps/someapp/env" && req.Method == "DELETE" && string(got) == want
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "app.env", "--prune"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `  SECRET is private in the app and will be left unchanged (use --private to update it)
+Changes to environment variables of app "someapp":
++ ADDED="here"
+~ CHANGED: "old" -> "new"
+- REMOVED="gone"
+Apply these changes to app "someapp"? (y/n) variable(s) successfully exported
+variable(s) successfully unset
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvSyncRunPrivate(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fsystem = &fstest.RecordingFs{FileContent: "PUBLIC=1\nSECRET=value\nADDED=here\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	current := `[{"name": "PUBLIC", "value": "1", "public": true}, {"name": "OTHER", "value": "x", "public": true},
+{"name": "SECRET", "value": "*** (private variable)", "public": false}]`
+	setMsg, err := json.Marshal(io.SimpleJsonMessage{Message: "variable(s) successfully exported\n"})
+	c.Assert(err, check.IsNil)
+	trans := &cmdtest.MultiConditionalTransport{
+		ConditionalTransports: []cmdtest.ConditionalTransport{
+			{
+				Transport: cmdtest.Transport{Message: current, Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					return req.URL.Path == "/apps/someapp/env" && req.Method == "GET"
+				},
+			},
+			{
+				Transport: cmdtest.Transport{Message: string(setMsg), Status: http.StatusOK},
+				CondFunc: func(req *http.Request) bool {
+					var got map[string]string
+					err := json.NewDecoder(req.Body).Decode(&got)
+					c.Assert(err, check.IsNil)
+					c.Assert(got, check.DeepEquals, map[string]string{"ADDED": "here", "PUBLIC": "1", "SECRET": "value"})
+					return req.URL.Path == "/apps/someapp/env" && req.Method == "POST" && req.URL.RawQuery == "private=1"
+				},
+			},
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "app.env", "--private", "-y"})
+	err = command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Changes to environment variables of app "someapp":
++ ADDED (private)
+~ PUBLIC: "1" -> (private)
+~ SECRET (private, current value can't be compared)
+variable(s) successfully exported
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvSyncRunInSync(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	fsystem = &fstest.RecordingFs{FileContent: "SAME=1\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	current := `[{"name": "SAME", "value": "1", "public": true}, {"name": "OTHER", "value": "x", "public": true}]`
+	client := cmd.NewClient(&http.Client{Transport: &cmdtest.Transport{Message: current, Status: http.StatusOK}}, nil, manager)
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "app.env"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, `App "someapp" is already in sync with "app.env".`+"\n")
+}
+
+func (s *S) TestEnvSyncRunWithoutConfirmation(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	fsystem = &fstest.RecordingFs{FileContent: "ADDED=1\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	trans := &cmdtest.ConditionalTransport{
+		Transport: cmdtest.Transport{Message: "[]", Status: http.StatusOK},
+		CondFunc: func(req *http.Request) bool {
+			return req.Method == "GET"
+		},
+	}
+	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "app.env"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `Changes to environment variables of app "someapp":
++ ADDED="1"
+Apply these changes to app "someapp"? (y/n) Abort.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestEnvSyncRunPrivateInSync(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	fsystem = &fstest.RecordingFs{FileContent: "SECRET=value\nTOKEN=t\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	api := newStubAPI().on("GET /apps/someapp/env", http.StatusOK, `[{"name": "SECRET", "value": "*** (private variable)", "public": false},
+{"name": "TOKEN", "value": "*** (private variable)", "public": false}]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "app.env", "--private"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `App "someapp" is in sync with "app.env", except for 2 private variable(s) whose current values can't be compared: SECRET, TOKEN
+Set them again? (y/n) Abort.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+	c.Assert(api.calls(), check.HasLen, 1)
+}
+
+func (s *S) TestEnvSyncRunStdinRequiresAssumeYes(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("ADDED=1\n"),
+	}
+	api := newStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "-"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `the changes can't be confirmed when the file is read from the standard input, use -y/--assume-yes to apply them`)
+	c.Assert(api.calls(), check.HasLen, 0)
+}
+
+func (s *S) TestEnvSyncRunStdinAssumeYes(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("ADDED=1\n"),
+	}
+	api := newStubAPI().
+		on("GET /apps/someapp/env", http.StatusOK, `[]`).
+		on("POST /apps/someapp/env", http.StatusOK, `{"Message":"variable(s) successfully exported\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "someapp", "-f", "-", "-y"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{"GET /apps/someapp/env", "POST /apps/someapp/env"})
+}
+
+func (s *S) TestEnvSyncRunWithoutFile(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "someapp"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "Please use the -f/--file flag to specify the file with the environment variables.")
+}
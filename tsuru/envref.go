@@ -0,0 +1,113 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/exec"
+)
+
+const (
+	envRefFile = "@file:"
+	envRefEnv  = "@env:"
+	envRefCmd  = "@cmd:"
+)
+
+// resolveEnvRefs replaces value references (@file:, @env: and @cmd:) with the
+// values they point to. Resolved variables are removed from variables and
+// returned in a separate map, so they can be set as private. A leading "@@"
+// escapes a literal "@".
+//
+// Resolved values are secrets: they must never be written to the output or
+// included in error messages.
+func resolveEnvRefs(context *cmd.Context, variables map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string)
+	for name, value := range variables {
+		if strings.HasPrefix(value, "@@") {
+			variables[name] = value[1:]
+			continue
+		}
+		var (
+			secret string
+			err    error
+		)
+		switch {
+		case strings.HasPrefix(value, envRefFile):
+			secret, err = resolveFileRef(strings.TrimPrefix(value, envRefFile))
+		case strings.HasPrefix(value, envRefEnv):
+			secret, err = resolveEnvRef(strings.TrimPrefix(value, envRefEnv))
+		case strings.HasPrefix(value, envRefCmd):
+			secret, err = resolveCmdRef(context, strings.TrimPrefix(value, envRefCmd))
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve the value of %s: %s", name, err)
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("unable to resolve the value of %s: the reference resolved to an empty value", name)
+		}
+		delete(variables, name)
+		resolved[name] = secret
+	}
+	return resolved, nil
+}
+
+func resolveFileRef(path string) (string, error) {
+	f, err := filesystem().Open(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("file %q doesn't exist", path)
+	} else if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return trimTrailingNewline(string(content)), nil
+}
+
+func resolveEnvRef(name string) (string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("local environment variable %q is not set", name)
+	}
+	return value, nil
+}
+
+// resolveCmdRef runs the given command line, split on whitespace and without
+// a shell, and returns its standard output. The command doesn't get the
+// standard input, which may be in use by env-set --file -.
+func resolveCmdRef(context *cmd.Context, cmdline string) (string, error) {
+	parts := strings.Fields(cmdline)
+	if len(parts) == 0 {
+		return "", errors.New("missing command")
+	}
+	var stdout bytes.Buffer
+	opts := exec.ExecuteOptions{
+		Cmd:    parts[0],
+		Args:   parts[1:],
+		Stdout: &stdout,
+		Stderr: context.Stderr,
+	}
+	err := executor().Execute(opts)
+	if err != nil {
+		return "", fmt.Errorf("command %q failed: %s", parts[0], err)
+	}
+	return trimTrailingNewline(stdout.String()), nil
+}
+
+func trimTrailingNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/exec/exectest"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+func (s *S) TestResolveEnvRefs(c *check.C) {
+	rfs := fstest.RecordingFs{FileContent: "s3cr3t\n"}
+	fsystem = &rfs
+	defer func() {
+		fsystem = nil
+	}()
+	fexec := exectest.FakeExecutor{
+		Output: map[string][][]byte{
+			"show db/token": {[]byte("t0k3n\n")},
+		},
+	}
+	execut = &fexec
+	defer func() {
+		execut = nil
+	}()
+	os.Setenv("TSURU_TEST_API_KEY", "k3y")
+	defer os.Setenv("TSURU_TEST_API_KEY", "")
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	variables := map[string]string{
+		"DB_PASS": "@file:secret.txt",
+		"API_KEY": "@env:TSURU_TEST_API_KEY",
+		"TOKEN":   "@cmd:pass show db/token",
+		"HANDLE":  "@@tsuru",
+		"HOST":    "somehost",
+	}
+	resolved, err := resolveEnvRefs(&context, variables)
+	c.Assert(err, check.IsNil)
+	c.Assert(resolved, check.DeepEquals, map[string]string{
+		"DB_PASS": "s3cr3t",
+		"API_KEY": "k3y",
+		"TOKEN":   "t0k3n",
+	})
+	c.Assert(variables, check.DeepEquals, map[string]string{
+		"HANDLE": "@tsuru",
+		"HOST":   "somehost",
+	})
+	c.Assert(rfs.HasAction("open secret.txt"), check.Equals, true)
+	c.Assert(fexec.ExecutedCmd("pass", []string{"show", "db/token"}), check.Equals, true)
+	c.Assert(stdout.String(), check.Equals, "")
+}
+
+func (s *S) TestResolveEnvRefsFileNotFound(c *check.C) {
+	fsystem = &fstest.FileNotFoundFs{}
+	defer func() {
+		fsystem = nil
+	}()
+	context := cmd.Context{}
+	_, err := resolveEnvRefs(&context, map[string]string{"DB_PASS": "@file:secret.txt"})
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `unable to resolve the value of DB_PASS: file "secret.txt" doesn't exist`)
+}
+
+func (s *S) TestResolveEnvRefsUnsetEnv(c *check.C) {
+	os.Setenv("TSURU_TEST_UNSET", "")
+	context := cmd.Context{}
+	_, err := resolveEnvRefs(&context, map[string]string{"API_KEY": "@env:TSURU_TEST_UNSET"})
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, `unable to resolve the value of API_KEY: local environment variable "TSURU_TEST_UNSET" is not set`)
+}
+
+func (s *S) TestResolveEnvRefsEmptyValue(c *check.C) {
+	rfs := fstest.RecordingFs{FileContent: "\n"}
+	fsystem = &rfs
+	defer func() {
+		fsystem = nil
+	}()
+	context := cmd.Context{}
+	_, err := resolveEnvRefs(&context, map[string]string{"DB_PASS": "@file:secret.txt"})
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "unable to resolve the value of DB_PASS: the reference resolved to an empty value")
+}
+
+func (s *S) TestResolveEnvRefsMissingCommand(c *check.C) {
+	context := cmd.Context{}
+	_, err := resolveEnvRefs(&context, map[string]string{"TOKEN": "@cmd:  "})
+	c.Assert(err, check.NotNil)
+	c.Assert(err.Error(), check.Equals, "unable to resolve the value of TOKEN: missing command")
+}
+
+func (s *S) TestResolveEnvRefsDoesNotLeakValues(c *check.C) {
+	fexec := exectest.FakeExecutor{
+		Output: map[string][][]byte{
+			"show db/token": {[]byte("t0k3n\n")},
+		},
+	}
+	execut = &fexec
+	defer func() {
+		execut = nil
+	}()
+	os.Setenv("TSURU_TEST_UNSET", "")
+	context := cmd.Context{}
+	_, err := resolveEnvRefs(&context, map[string]string{
+		"TOKEN":   "@cmd:pass show db/token",
+		"API_KEY": "@env:TSURU_TEST_UNSET",
+	})
+	c.Assert(err, check.NotNil)
+	c.Assert(strings.Contains(err.Error(), "t0k3n"), check.Equals, false)
+}
@@ -5,6 +5,10 @@
 package main
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
 	"github.com/tsuru/tsuru/fs"
 )
 
@@ -16,3 +20,14 @@ func filesystem() fs.Fs {
 	}
 	return fsystem
 }
+
+func readLocalFile(path string) ([]byte, error) {
+	f, err := filesystem().Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("file %q doesn't exist", path)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v1"
+)
+
+// checkFormat returns an error if format is not empty and is not one of the
+// given valid formats.
+func checkFormat(format string, valid ...string) error {
+	if format == "" {
+		return nil
+	}
+	for _, f := range valid {
+		if f == format {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid format %q, valid formats are: %s", format, strings.Join(valid, ", "))
+}
+
+// renderStructured writes data to w as indented JSON or as YAML.
+func renderStructured(w io.Writer, format string, data interface{}) error {
+	var (
+		b   []byte
+		err error
+	)
+	switch format {
+	case "json":
+		b, err = json.MarshalIndent(data, "", "  ")
+		b = append(b, '\n')
+	case "yaml":
+		b, err = yaml.Marshal(data)
+	default:
+		return fmt.Errorf("invalid format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tsuru/tsuru/cmd"
+	"launchpad.net/gnuflag"
+)
+
+const lockPollInterval = 2 * time.Second
+
+// lockWaiter adds the --wait-lock flag to commands that change apps. Commands
+// embed it and call waitLock before sending the change to the API.
+type lockWaiter struct {
+	fs          *gnuflag.FlagSet
+	lockTimeout time.Duration
+}
+
+func (w *lockWaiter) Flags() *gnuflag.FlagSet {
+	if w.fs == nil {
+		w.fs = gnuflag.NewFlagSet("", gnuflag.ExitOnError)
+		w.fs.DurationVar(&w.lockTimeout, "wait-lock", 0, "Wait up to the given duration for the app to be unlocked")
+	}
+	return w.fs
+}
+
+// waitLock waits for the app to be unlocked, reporting who holds the lock.
+// It does nothing unless --wait-lock was used.
+func (w *lockWaiter) waitLock(out io.Writer, client *cmd.Client, appName string) error {
+	if w.lockTimeout <= 0 {
+		return nil
+	}
+	var (
+		elapsed time.Duration
+		last    lock
+	)
+	for {
+		a, err := getApp(client, appName)
+		if err != nil {
+			return err
+		}
+		if !a.Lock.Locked {
+			if last.Locked {
+				fmt.Fprintf(out, "App %q was unlocked.\n", appName)
+			}
+			return nil
+		}
+		if a.Lock.Owner != last.Owner || a.Lock.Reason != last.Reason {
+			fmt.Fprintf(out, "App %q is locked by %s since %s: %s\n", appName, a.Lock.Owner, a.Lock.AcquireDate.Local().Format(time.Stamp), a.Lock.Reason)
+			fmt.Fprintf(out, "Waiting up to %s for the lock to be released...\n", w.lockTimeout)
+		}
+		last = a.Lock
+		if elapsed >= w.lockTimeout {
+			return fmt.Errorf("timed out after %s waiting for app %q to be unlocked, it's still locked by %s: %s",
+				w.lockTimeout, appName, a.Lock.Owner, a.Lock.Reason)
+		}
+		<-watchTick(lockPollInterval)
+		elapsed += lockPollInterval
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/cmd"
+	"github.com/tsuru/tsuru/fs/fstest"
+	"gopkg.in/check.v1"
+)
+
+const lockedApp = `{"name":"myapp","lock":{"locked":true,"owner":"admin@example.com","reason":"POST /apps/myapp/restart","acquiredate":"2015-06-01T10:32:00Z"}}`
+
+const unlockedApp = `{"name":"myapp","lock":{"locked":false}}`
+
+func (s *S) TestWaitLock(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Args: []string{"2"}, Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, lockedApp).
+		on("GET /apps/myapp", http.StatusOK, lockedApp).
+		on("GET /apps/myapp", http.StatusOK, unlockedApp).
+		on("PUT /apps/myapp/units", http.StatusOK, `{"Message":"units added\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := unitAdd{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--wait-lock", "1m"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/myapp",
+		"GET /apps/myapp",
+		"GET /apps/myapp",
+		"PUT /apps/myapp/units",
+	})
+	c.Assert(stdout.String(), check.Matches, `App "myapp" is locked by admin@example.com since .*: POST /apps/myapp/restart
+Waiting up to 1m0s for the lock to be released...
+App "myapp" was unlocked.
+units added
+`)
+}
+
+func (s *S) TestWaitLockTimeout(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, lockedApp).
+		on("POST /apps/myapp/restart", http.StatusOK, `{"Message":"restarted\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appRestart{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--wait-lock", "10s"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `timed out after 10s waiting for app "myapp" to be unlocked, it's still locked by admin@example.com: POST /apps/myapp/restart`)
+	for _, call := range api.calls() {
+		c.Assert(call, check.Equals, "GET /apps/myapp")
+	}
+	c.Assert(api.calls(), check.HasLen, 6)
+	c.Assert(bytes.Count(stdout.Bytes(), []byte("is locked by")), check.Equals, 1)
+}
+
+func (s *S) TestWaitLockNotRequested(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Args: []string{"FOO"}, Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().
+		on("DELETE /apps/myapp/env", http.StatusOK, `{"Message":"variable unset\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := envUnset{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{"DELETE /apps/myapp/env"})
+}
+
+func (s *S) TestWaitLockAfterConfirmation(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	fsystem = &fstest.RecordingFs{FileContent: "DEBUG=0\n"}
+	defer func() {
+		fsystem = nil
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr, Stdin: strings.NewReader("y\n")}
+	api := newStubAPI().
+		on("GET /apps/myapp/env", http.StatusOK, `[{"name":"DEBUG","value":"1","public":true}]`).
+		on("GET /apps/myapp", http.StatusOK, lockedApp).
+		on("GET /apps/myapp", http.StatusOK, unlockedApp).
+		on("POST /apps/myapp/env", http.StatusOK, `{"Message":"variable(s) successfully exported\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := envSync{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "-f", ".env", "--wait-lock", "1m"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/myapp/env",
+		"GET /apps/myapp",
+		"GET /apps/myapp",
+		"POST /apps/myapp/env",
+	})
+	c.Assert(stdout.String(), check.Matches, `(?s).*\(y/n\) App "myapp" is locked by admin@example.com .*App "myapp" was unlocked.\n.*`)
+}
@@ -24,10 +24,14 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(&appRun{})
 	m.Register(&appInfo{})
 	m.Register(&appCreate{})
+	m.Register(&appClone{})
+	m.Register(&appApply{})
 	m.Register(&appRemove{})
 	m.Register(&unitAdd{})
 	m.Register(&unitRemove{})
+	m.Register(&appScale{})
 	m.Register(&appList{})
+	m.Register(&appDashboard{})
 	m.Register(&appLog{})
 	m.Register(&appGrant{})
 	m.Register(&appRevoke{})
@@ -38,9 +42,14 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(&appPlanChange{})
 	m.Register(&cnameAdd{})
 	m.Register(&cnameRemove{})
+	m.Register(&cnameSync{})
+	m.Register(&cnameList{})
 	m.Register(&envGet{})
 	m.Register(&envSet{})
 	m.Register(&envUnset{})
+	m.Register(&envDiff{})
+	m.Register(&envCopy{})
+	m.Register(&envSync{})
 	m.Register(&keyAdd{})
 	m.Register(&keyRemove{})
 	m.Register(&keyList{})
@@ -54,13 +63,15 @@ func buildManager(name string) *cmd.Manager {
 	m.Register(&serviceInstanceRevoke{})
 	m.Register(&serviceBind{})
 	m.Register(&serviceUnbind{})
-	m.Register(platformList{})
+	m.Register(&platformList{})
 	m.Register(&pluginInstall{})
 	m.Register(&pluginRemove{})
 	m.Register(&pluginList{})
 	m.Register(&appSwap{})
 	m.Register(&appDeploy{})
 	m.Register(&planList{})
+	m.Register(&planCompare{})
+	m.Register(&appCost{})
 	m.RegisterDeprecated(&TeamOwnerSet{}, "app-set-team-owner")
 	m.Register(&userCreate{})
 	m.Register(&resetPassword{})
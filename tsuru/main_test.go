@@ -29,6 +29,20 @@ func (s *S) TestAppCreateIsRegistered(c *check.C) {
 	c.Assert(create, check.FitsTypeOf, &appCreate{})
 }
 
+func (s *S) TestAppCloneIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	clone, ok := manager.Commands["app-clone"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(clone, check.FitsTypeOf, &appClone{})
+}
+
+func (s *S) TestAppApplyIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	apply, ok := manager.Commands["app-apply"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(apply, check.FitsTypeOf, &appApply{})
+}
+
 func (s *S) TestAppRemoveIsRegistered(c *check.C) {
 	manager := buildManager("tsuru")
 	remove, ok := manager.Commands["app-remove"]
@@ -43,6 +57,13 @@ func (s *S) TestAppListIsRegistered(c *check.C) {
 	c.Assert(list, check.FitsTypeOf, &appList{})
 }
 
+func (s *S) TestAppDashboardIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	dashboard, ok := manager.Commands["app-dashboard"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(dashboard, check.FitsTypeOf, &appDashboard{})
+}
+
 func (s *S) TestAppGrantIsRegistered(c *check.C) {
 	manager := buildManager("tsuru")
 	grant, ok := manager.Commands["app-grant"]
@@ -99,6 +120,27 @@ func (s *S) TestEnvUnsetIsRegistered(c *check.C) {
 	c.Assert(unset, check.FitsTypeOf, &envUnset{})
 }
 
+func (s *S) TestEnvDiffIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	diff, ok := manager.Commands["env-diff"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(diff, check.FitsTypeOf, &envDiff{})
+}
+
+func (s *S) TestEnvCopyIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	copy, ok := manager.Commands["env-copy"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(copy, check.FitsTypeOf, &envCopy{})
+}
+
+func (s *S) TestEnvSyncIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	sync, ok := manager.Commands["env-sync"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(sync, check.FitsTypeOf, &envSync{})
+}
+
 func (s *S) TestKeyAddIsRegistered(c *check.C) {
 	manager := buildManager("tsuru")
 	add, ok := manager.Commands["key-add"]
@@ -197,6 +239,13 @@ func (s *S) TestUnitRemoveIsRegistered(c *check.C) {
 	c.Assert(rmunit, check.FitsTypeOf, &unitRemove{})
 }
 
+func (s *S) TestAppScaleIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	scale, ok := manager.Commands["app-scale"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(scale, check.FitsTypeOf, &appScale{})
+}
+
 func (s *S) TestCNameAddIsRegistered(c *check.C) {
 	manager := buildManager("tsuru")
 	cname, ok := manager.Commands["cname-add"]
@@ -211,11 +260,25 @@ func (s *S) TestCNameRemoveIsRegistered(c *check.C) {
 	c.Assert(cname, check.FitsTypeOf, &cnameRemove{})
 }
 
+func (s *S) TestCNameSyncIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	cname, ok := manager.Commands["cname-sync"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(cname, check.FitsTypeOf, &cnameSync{})
+}
+
+func (s *S) TestCNameListIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	cname, ok := manager.Commands["cname-list"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(cname, check.FitsTypeOf, &cnameList{})
+}
+
 func (s *S) TestPlatformListIsRegistered(c *check.C) {
 	manager := buildManager("tsuru")
 	plat, ok := manager.Commands["platform-list"]
 	c.Assert(ok, check.Equals, true)
-	c.Assert(plat, check.FitsTypeOf, platformList{})
+	c.Assert(plat, check.FitsTypeOf, &platformList{})
 }
 
 func (s *S) TestAppSwapIsRegistered(c *check.C) {
@@ -379,6 +442,20 @@ func (s *S) TestTeamOwnerSetIsRegistered(c *check.C) {
 	c.Assert("app-team-owner-set", Deprecates, "app-set-team-owner")
 }
 
+func (s *S) TestPlanCompareIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	compare, ok := manager.Commands["plan-compare"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(compare, check.FitsTypeOf, &planCompare{})
+}
+
+func (s *S) TestAppCostIsRegistered(c *check.C) {
+	manager := buildManager("tsuru")
+	cost, ok := manager.Commands["app-cost"]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(cost, check.FitsTypeOf, &appCost{})
+}
+
 func (s *S) TestAppPlanChangeIsRegistered(c *check.C) {
 	manager := buildManager("tsuru")
 	change, ok := manager.Commands["app-plan-change"]
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -17,8 +18,9 @@ import (
 )
 
 type planList struct {
-	human bool
-	fs    *gnuflag.FlagSet
+	human  bool
+	format string
+	fs     *gnuflag.FlagSet
 }
 
 func (c *planList) Flags() *gnuflag.FlagSet {
@@ -27,15 +29,19 @@ func (c *planList) Flags() *gnuflag.FlagSet {
 		human := "Humanized units for memory and swap."
 		c.fs.BoolVar(&c.human, "human", false, human)
 		c.fs.BoolVar(&c.human, "h", false, human)
+		c.fs.StringVar(&c.format, "format", "table", "Output format: table, json or yaml")
 	}
 	return c.fs
 }
 
 func (c *planList) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "plan-list",
-		Usage:   "plan-list [--human]",
-		Desc:    "List available plans that can be used when creating an app.",
+		Name:  "plan-list",
+		Usage: "plan-list [--human] [--format table|json|yaml]",
+		Desc: `List available plans that can be used when creating an app.
+
+With [[--format]] json or yaml, all the fields of the plans are shown, with
+memory and swap in bytes.`,
 		MinArgs: 0,
 	}
 }
@@ -46,8 +52,8 @@ func renderPlans(plans []tsuruapp.Plan, isHuman bool) string {
 	for _, p := range plans {
 		var memory, swap string
 		if isHuman {
-			memory = fmt.Sprintf("%d MB", p.Memory/1024/1024)
-			swap = fmt.Sprintf("%d MB", p.Swap/1024/1024)
+			memory = humanSize(p.Memory)
+			swap = humanSize(p.Swap)
 		} else {
 			memory = fmt.Sprintf("%d", p.Memory)
 			swap = fmt.Sprintf("%d", p.Swap)
@@ -62,24 +68,25 @@ func renderPlans(plans []tsuruapp.Plan, isHuman bool) string {
 	return table.String()
 }
 
+// humanSize formats a size in bytes as megabytes.
+func humanSize(size int64) string {
+	return fmt.Sprintf("%d MB", size/1024/1024)
+}
+
 func (c *planList) Run(context *cmd.Context, client *cmd.Client) error {
-	url, err := cmd.GetURL("/plans")
+	err := checkFormat(c.format, "table", "json", "yaml")
 	if err != nil {
 		return err
 	}
-	request, err := http.NewRequest("GET", url, nil)
+	plans, err := listPlans(client)
 	if err != nil {
 		return err
 	}
-	var plans []tsuruapp.Plan
-	resp, err := client.Do(request)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	err = json.NewDecoder(resp.Body).Decode(&plans)
-	if err != nil {
-		return err
+	if c.format == "json" || c.format == "yaml" {
+		if plans == nil {
+			plans = []tsuruapp.Plan{}
+		}
+		return renderStructured(context.Stdout, c.format, plans)
 	}
 	if len(plans) == 0 {
 		fmt.Fprintln(context.Stdout, "No plans available.")
@@ -89,17 +96,47 @@ func (c *planList) Run(context *cmd.Context, client *cmd.Client) error {
 	return nil
 }
 
+// listPlans returns the plans available in the tsuru server.
+func listPlans(client *cmd.Client) ([]tsuruapp.Plan, error) {
+	url, err := cmd.GetURL("/plans")
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var plans []tsuruapp.Plan
+	err = json.NewDecoder(resp.Body).Decode(&plans)
+	if err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
 type appPlanChange struct {
 	fs *gnuflag.FlagSet
 	cmd.GuessingCommand
+	lockWaiter
 	cmd.ConfirmationCommand
+	dryRun bool
 }
 
 func (c *appPlanChange) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "app-plan-change",
-		Usage:   "app-plan-change <plan_name> [-a/--app appname] [-y/--assume-yes]",
-		Desc:    "Change the plan of the application.",
+		Name:  "app-plan-change",
+		Usage: "app-plan-change <plan_name> [-a/--app appname] [-y/--assume-yes] [--dry-run] [--wait-lock 5m]",
+		Desc: `Change the plan of the application.
+
+Before changing the plan, the command shows the current plan of the app and the
+new plan side by side, and warns when the router changes, since that may change
+the address of the app. The [[--dry-run]] flag shows the comparison without
+changing the plan.`,
 		MinArgs: 1,
 	}
 }
@@ -110,16 +147,55 @@ func (c *appPlanChange) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	plan := tsuruapp.Plan{Name: context.Args[0]}
+	a, err := getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	plans, err := listPlans(client)
+	if err != nil {
+		return err
+	}
+	var plan *tsuruapp.Plan
+	for i := range plans {
+		if plans[i].Name == context.Args[0] {
+			plan = &plans[i]
+			break
+		}
+	}
+	if plan == nil {
+		return fmt.Errorf("plan %q not found, run plan-list to see the available plans", context.Args[0])
+	}
+	if a.Plan.Name == plan.Name {
+		fmt.Fprintf(context.Stdout, "App %q already uses plan %q.\n", appName, plan.Name)
+		return nil
+	}
+	fmt.Fprintf(context.Stdout, "Current plan (%s) and new plan (%s) of app %q:\n", a.Plan.Name, plan.Name, appName)
+	fmt.Fprint(context.Stdout, renderPlans([]tsuruapp.Plan{a.Plan, *plan}, true))
+	if a.Plan.Router != plan.Router {
+		fmt.Fprintf(context.Stdout, "WARNING: the router changes from %q to %q, the address of the app may change.\n", a.Plan.Router, plan.Router)
+	}
+	if c.dryRun {
+		return nil
+	}
 	question := fmt.Sprintf("Are you sure you want to change the plan of the application %q to %q?", appName, plan.Name)
 	if !c.Confirm(context, question) {
 		return nil
 	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
+	return changePlan(context.Stdout, client, appName, plan.Name)
+}
+
+// changePlan changes the plan of the app, streaming the output of the API to
+// w.
+func changePlan(w io.Writer, client *cmd.Client, appName, planName string) error {
 	url, err := cmd.GetURL(fmt.Sprintf("/apps/%s/plan", appName))
 	if err != nil {
 		return err
 	}
-	b, err := json.Marshal(plan)
+	b, err := json.Marshal(tsuruapp.Plan{Name: planName})
 	if err != nil {
 		return err
 	}
@@ -131,12 +207,14 @@ func (c *appPlanChange) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	return cmd.StreamJSONResponse(context.Stdout, response)
+	return cmd.StreamJSONResponse(w, response)
 }
 
 func (c *appPlanChange) Flags() *gnuflag.FlagSet {
 	if c.fs == nil {
 		c.fs = cmd.MergeFlagSet(c.ConfirmationCommand.Flags(), c.GuessingCommand.Flags())
+		c.fs = cmd.MergeFlagSet(c.fs, c.lockWaiter.Flags())
+		c.fs.BoolVar(&c.dryRun, "dry-run", false, "Show the current and the new plan, without changing the plan")
 	}
 	return c.fs
 }
@@ -13,8 +13,8 @@ import (
 	tsuruapp "github.com/tsuru/tsuru/app"
 	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
-	"github.com/tsuru/tsuru/io"
 	"gopkg.in/check.v1"
+	"gopkg.in/yaml.v1"
 )
 
 func (s *S) TestPlanListInfo(c *check.C) {
@@ -84,70 +84,215 @@ func (s *S) TestPlanListHuman(c *check.C) {
 	c.Assert(stdout.String(), check.Equals, expected)
 }
 
+func (s *S) TestPlanListJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /plans", http.StatusOK, planChangePlans)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := planList{}
+	command.Flags().Parse(true, []string{"--format", "json", "--human"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var plans []tsuruapp.Plan
+	err = json.Unmarshal(stdout.Bytes(), &plans)
+	c.Assert(err, check.IsNil)
+	c.Assert(plans, check.DeepEquals, []tsuruapp.Plan{
+		{Name: "small", Memory: 268435456, Swap: 134217728, CpuShare: 50, Router: "hipache", Default: true},
+		{Name: "hiperplan", Memory: 1073741824, Swap: 536870912, CpuShare: 200, Router: "hipache"},
+		{Name: "vulcan", Memory: 1073741824, Swap: 536870912, CpuShare: 200, Router: "vulcand"},
+	})
+}
+
+func (s *S) TestPlanListYAML(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /plans", http.StatusOK, `[{"name":"small","memory":268435456,"swap":134217728,"cpushare":50,"router":"hipache","default":true}]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := planList{}
+	command.Flags().Parse(true, []string{"--format", "yaml"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var plans []tsuruapp.Plan
+	err = yaml.Unmarshal(stdout.Bytes(), &plans)
+	c.Assert(err, check.IsNil)
+	c.Assert(plans, check.DeepEquals, []tsuruapp.Plan{
+		{Name: "small", Memory: 268435456, Swap: 134217728, CpuShare: 50, Router: "hipache", Default: true},
+	})
+}
+
+func (s *S) TestPlanListJSONEmpty(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /plans", http.StatusOK, `[]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := planList{}
+	command.Flags().Parse(true, []string{"--format", "json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "[]\n")
+}
+
+func (s *S) TestPlanListInvalidFormat(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := planList{}
+	command.Flags().Parse(true, []string{"--format", "csv"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `invalid format "csv", valid formats are: table, json, yaml`)
+	c.Assert(api.calls(), check.HasLen, 0)
+}
+
+const planChangeApp = `{"name":"handful_of_nothing","plan":{"name":"small","memory":268435456,"swap":134217728,"cpushare":50,"router":"hipache","default":true}}`
+
+const planChangePlans = `[
+{"name":"small","memory":268435456,"swap":134217728,"cpushare":50,"router":"hipache","default":true},
+{"name":"hiperplan","memory":1073741824,"swap":536870912,"cpushare":200,"router":"hipache","default":false},
+{"name":"vulcan","memory":1073741824,"swap":536870912,"cpushare":200,"router":"vulcand","default":false}
+]`
+
+func planChangeStubAPI() *stubAPI {
+	return newStubAPI().
+		on("GET /apps/handful_of_nothing", http.StatusOK, planChangeApp).
+		on("GET /plans", http.StatusOK, planChangePlans).
+		on("POST /apps/handful_of_nothing/plan", http.StatusOK, `{"Message":"-- plan changed --"}`)
+}
+
+const planChangeComparison = `Current plan (small) and new plan (hiperplan) of app "handful_of_nothing":
++-----------+---------+--------+-----------+---------+---------+
+| Name      | Memory  | Swap   | Cpu Share | Router  | Default |
++-----------+---------+--------+-----------+---------+---------+
+| small     | 256 MB  | 128 MB | 50        | hipache | true    |
+| hiperplan | 1024 MB | 512 MB | 200       | hipache | false   |
++-----------+---------+--------+-----------+---------+---------+
+`
+
 func (s *S) TestAppPlanChange(c *check.C) {
-	var (
-		called         bool
-		stdout, stderr bytes.Buffer
-	)
+	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
 		Stdout: &stdout,
 		Stderr: &stderr,
 		Args:   []string{"hiperplan"},
 	}
-	expectedOut := "-- plan changed --"
-	msg := io.SimpleJsonMessage{Message: expectedOut}
-	result, err := json.Marshal(msg)
-	c.Assert(err, check.IsNil)
-	trans := &cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
-		CondFunc: func(req *http.Request) bool {
-			called = true
-			var plan tsuruapp.Plan
-			json.NewDecoder(req.Body).Decode(&plan)
-			c.Assert(plan.Name, check.Equals, "hiperplan")
-			return req.URL.Path == "/apps/handful_of_nothing/plan" && req.Method == "POST"
-		},
-	}
-	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	api := planChangeStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
 	var command appPlanChange
 	command.Flags().Parse(true, []string{"--app", "handful_of_nothing", "-y"})
-	err = command.Run(&context, client)
+	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
-	c.Assert(called, check.Equals, true)
-	c.Assert(stdout.String(), check.Equals, expectedOut)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/handful_of_nothing",
+		"GET /plans",
+		"POST /apps/handful_of_nothing/plan",
+	})
+	var plan tsuruapp.Plan
+	err = json.Unmarshal([]byte(api.bodies[2]), &plan)
+	c.Assert(err, check.IsNil)
+	c.Assert(plan.Name, check.Equals, "hiperplan")
+	c.Assert(stdout.String(), check.Equals, planChangeComparison+"-- plan changed --")
 }
 
 func (s *S) TestAppPlanChangeAsk(c *check.C) {
-	var (
-		called         bool
-		stdout, stderr bytes.Buffer
-	)
+	var stdout, stderr bytes.Buffer
 	context := cmd.Context{
 		Stdout: &stdout,
 		Stderr: &stderr,
 		Stdin:  strings.NewReader("y\n"),
 		Args:   []string{"hiperplan"},
 	}
-	expectedOut := "-- plan changed --"
-	msg := io.SimpleJsonMessage{Message: expectedOut}
-	result, err := json.Marshal(msg)
+	api := planChangeStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	var command appPlanChange
+	command.Flags().Parse(true, []string{"--app", "handful_of_nothing"})
+	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
-	trans := &cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{Message: string(result), Status: http.StatusOK},
-		CondFunc: func(req *http.Request) bool {
-			called = true
-			var plan tsuruapp.Plan
-			json.NewDecoder(req.Body).Decode(&plan)
-			c.Assert(plan.Name, check.Equals, "hiperplan")
-			return req.URL.Path == "/apps/handful_of_nothing/plan" && req.Method == "POST"
-		},
+	c.Assert(api.calls(), check.HasLen, 3)
+	expectedOut := planChangeComparison + `Are you sure you want to change the plan of the application "handful_of_nothing" to "hiperplan"? (y/n) -- plan changed --`
+	c.Assert(stdout.String(), check.Equals, expectedOut)
+}
+
+func (s *S) TestAppPlanChangeDeclinedDoesNotWaitLock(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+		Args:   []string{"hiperplan"},
 	}
-	client := cmd.NewClient(&http.Client{Transport: trans}, nil, manager)
+	api := planChangeStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	var command appPlanChange
+	command.Flags().Parse(true, []string{"--app", "handful_of_nothing", "--wait-lock", "1m"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/handful_of_nothing",
+		"GET /plans",
+	})
+}
+
+func (s *S) TestAppPlanChangeDryRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Args:   []string{"hiperplan"},
+	}
+	api := planChangeStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	var command appPlanChange
+	command.Flags().Parse(true, []string{"--app", "handful_of_nothing", "--dry-run"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/handful_of_nothing",
+		"GET /plans",
+	})
+	c.Assert(stdout.String(), check.Equals, planChangeComparison)
+}
+
+func (s *S) TestAppPlanChangeRouterWarning(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Stdin:  strings.NewReader("n\n"),
+		Args:   []string{"vulcan"},
+	}
+	api := planChangeStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
 	var command appPlanChange
 	command.Flags().Parse(true, []string{"--app", "handful_of_nothing"})
-	err = command.Run(&context, client)
+	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
-	c.Assert(called, check.Equals, true)
-	expectedOut = `Are you sure you want to change the plan of the application "handful_of_nothing" to "hiperplan"? (y/n) -- plan changed --`
-	c.Assert(stdout.String(), check.Equals, expectedOut)
+	c.Assert(api.calls(), check.HasLen, 2)
+	c.Assert(stdout.String(), check.Matches, `(?s).*\| vulcan +\| 1024 MB +\| 512 MB +\| 200 +\| vulcand +\| false +\|.*`)
+	c.Assert(stdout.String(), check.Matches, `(?s).*WARNING: the router changes from "hipache" to "vulcand", the address of the app may change.
+Are you sure.*`)
+}
+
+func (s *S) TestAppPlanChangeSamePlan(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr, Args: []string{"small"}}
+	api := planChangeStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	var command appPlanChange
+	command.Flags().Parse(true, []string{"--app", "handful_of_nothing", "-y"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.HasLen, 2)
+	c.Assert(stdout.String(), check.Equals, `App "handful_of_nothing" already uses plan "small".`+"\n")
+}
+
+func (s *S) TestAppPlanChangePlanNotFound(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr, Args: []string{"huge"}}
+	api := planChangeStubAPI()
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	var command appPlanChange
+	command.Flags().Parse(true, []string{"--app", "handful_of_nothing", "-y"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `plan "huge" not found, run plan-list to see the available plans`)
+	c.Assert(api.calls(), check.HasLen, 2)
 }
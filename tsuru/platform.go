@@ -11,6 +11,7 @@ import (
 	"sort"
 
 	"github.com/tsuru/tsuru/cmd"
+	"launchpad.net/gnuflag"
 )
 
 type platform struct {
@@ -18,9 +19,24 @@ type platform struct {
 	Disabled bool
 }
 
-type platformList struct{}
+type platformList struct {
+	fs     *gnuflag.FlagSet
+	format string
+}
+
+func (c *platformList) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("platform-list", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.format, "format", "table", "Output format: table, json or yaml")
+	}
+	return c.fs
+}
 
-func (platformList) Run(context *cmd.Context, client *cmd.Client) error {
+func (c *platformList) Run(context *cmd.Context, client *cmd.Client) error {
+	err := checkFormat(c.format, "table", "json", "yaml")
+	if err != nil {
+		return err
+	}
 	url, err := cmd.GetURL("/platforms")
 	if err != nil {
 		return err
@@ -39,6 +55,14 @@ func (platformList) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	sort.Sort(platformsByName(platforms))
+	switch c.format {
+	case "json", "yaml":
+		if platforms == nil {
+			platforms = []platform{}
+		}
+		return renderStructured(context.Stdout, c.format, platforms)
+	}
 	if len(platforms) == 0 {
 		fmt.Fprintln(context.Stdout, "No platforms available.")
 		return nil
@@ -50,18 +74,26 @@ func (platformList) Run(context *cmd.Context, client *cmd.Client) error {
 			platformNames[i] += " (disabled)"
 		}
 	}
-	sort.Strings(platformNames)
 	for _, p := range platformNames {
 		fmt.Fprintf(context.Stdout, "- %s\n", p)
 	}
 	return nil
 }
 
-func (platformList) Info() *cmd.Info {
+func (c *platformList) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "platform-list",
-		Usage:   "platform-list",
-		Desc:    "Lists the available platforms. All platforms displayed in this list may be used to create new apps (see app-create).",
+		Name:  "platform-list",
+		Usage: "platform-list [--format table|json|yaml]",
+		Desc: `Lists the available platforms. All platforms displayed in this list may be used to create new apps (see app-create).
+
+By default, the platforms are listed one per line, marking the disabled ones.
+With [[--format]] json or yaml, the platforms are shown in the given format.`,
 		MinArgs: 0,
 	}
 }
+
+type platformsByName []platform
+
+func (l platformsByName) Len() int           { return len(l) }
+func (l platformsByName) Less(i, j int) bool { return l[i].Name < l[j].Name }
+func (l platformsByName) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
@@ -6,11 +6,13 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 
 	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"gopkg.in/check.v1"
+	"gopkg.in/yaml.v1"
 )
 
 func (s *S) TestPlatformList(c *check.C) {
@@ -28,7 +30,7 @@ func (s *S) TestPlatformList(c *check.C) {
 	}
 	context := cmd.Context{Stdout: &buf}
 	client := cmd.NewClient(&http.Client{Transport: &transport}, nil, manager)
-	err := platformList{}.Run(&context, client)
+	err := (&platformList{}).Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(called, check.Equals, true)
 	expected := `- python
@@ -51,7 +53,7 @@ func (s *S) TestPlatformListWithDisabledPlatforms(c *check.C) {
 	}
 	context := cmd.Context{Stdout: &buf}
 	client := cmd.NewClient(&http.Client{Transport: &transport}, nil, manager)
-	err := platformList{}.Run(&context, client)
+	err := (&platformList{}).Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(called, check.Equals, true)
 	expected := `- python
@@ -68,15 +70,73 @@ func (s *S) TestPlatformListEmpty(c *check.C) {
 	}
 	context := cmd.Context{Stdout: &buf}
 	client := cmd.NewClient(&http.Client{Transport: &transport}, nil, manager)
-	err := platformList{}.Run(&context, client)
+	err := (&platformList{}).Run(&context, client)
 	c.Assert(err, check.IsNil)
 	c.Assert(buf.String(), check.Equals, "No platforms available.\n")
 }
 
+func (s *S) TestPlatformListTable(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	api := newStubAPI().on("GET /platforms", http.StatusOK, `[{"Name":"ruby"},{"Name":"python"},{"Name":"ruby20", "Disabled":true}]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := platformList{}
+	command.Flags().Parse(true, []string{"--format", "table"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	expected := `- python
+- ruby
+- ruby20 (disabled)
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestPlatformListJSON(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	api := newStubAPI().on("GET /platforms", http.StatusOK, `[{"Name":"ruby"},{"Name":"python","Disabled":true}]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := platformList{}
+	command.Flags().Parse(true, []string{"--format", "json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var platforms []platform
+	err = json.Unmarshal(stdout.Bytes(), &platforms)
+	c.Assert(err, check.IsNil)
+	c.Assert(platforms, check.DeepEquals, []platform{{Name: "python", Disabled: true}, {Name: "ruby"}})
+}
+
+func (s *S) TestPlatformListYAML(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	api := newStubAPI().on("GET /platforms", http.StatusOK, `[{"Name":"ruby"}]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := platformList{}
+	command.Flags().Parse(true, []string{"--format", "yaml"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var platforms []platform
+	err = yaml.Unmarshal(stdout.Bytes(), &platforms)
+	c.Assert(err, check.IsNil)
+	c.Assert(platforms, check.DeepEquals, []platform{{Name: "ruby"}})
+}
+
+func (s *S) TestPlatformListJSONEmpty(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	api := newStubAPI().on("GET /platforms", http.StatusOK, `[]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := platformList{}
+	command.Flags().Parse(true, []string{"--format", "json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(stdout.String(), check.Equals, "[]\n")
+}
+
 func (s *S) TestPlatformListInfo(c *check.C) {
-	c.Assert(platformList{}.Info(), check.NotNil)
+	c.Assert((&platformList{}).Info(), check.NotNil)
 }
 
 func (s *S) TestPlatformListIsACommand(c *check.C) {
-	var _ cmd.Command = platformList{}
+	var _ cmd.Command = &platformList{}
 }
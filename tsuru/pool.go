@@ -6,9 +6,13 @@ import (
 	"strings"
 
 	"github.com/tsuru/tsuru/cmd"
+	"launchpad.net/gnuflag"
 )
 
-type poolList struct{}
+type poolList struct {
+	fs     *gnuflag.FlagSet
+	format string
+}
 
 type PoolsByTeam struct {
 	Team  string
@@ -20,11 +24,23 @@ type Pool struct {
 }
 
 type ListPoolResponse struct {
-	PoolsByTeam []PoolsByTeam `json:"pools_by_team"`
-	PublicPools []Pool        `json:"public_pools"`
+	PoolsByTeam []PoolsByTeam `json:"pools_by_team" yaml:"pools_by_team"`
+	PublicPools []Pool        `json:"public_pools" yaml:"public_pools"`
 }
 
-func (poolList) Run(context *cmd.Context, client *cmd.Client) error {
+func (c *poolList) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = gnuflag.NewFlagSet("pool-list", gnuflag.ExitOnError)
+		c.fs.StringVar(&c.format, "format", "table", "Output format: table, json or yaml")
+	}
+	return c.fs
+}
+
+func (c *poolList) Run(context *cmd.Context, client *cmd.Client) error {
+	err := checkFormat(c.format, "table", "json", "yaml")
+	if err != nil {
+		return err
+	}
 	url, err := cmd.GetURL("/pools")
 	if err != nil {
 		return err
@@ -43,6 +59,15 @@ func (poolList) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
+	if c.format == "json" || c.format == "yaml" {
+		if pools.PoolsByTeam == nil {
+			pools.PoolsByTeam = []PoolsByTeam{}
+		}
+		if pools.PublicPools == nil {
+			pools.PublicPools = []Pool{}
+		}
+		return renderStructured(context.Stdout, c.format, pools)
+	}
 	t := cmd.Table{Headers: cmd.Row([]string{"Team", "Pools"})}
 	for _, pool := range pools.PoolsByTeam {
 		t.AddRow(cmd.Row([]string{pool.Team, strings.Join(pool.Pools, ", ")}))
@@ -59,11 +84,14 @@ func (poolList) Run(context *cmd.Context, client *cmd.Client) error {
 	return nil
 }
 
-func (poolList) Info() *cmd.Info {
+func (c *poolList) Info() *cmd.Info {
 	return &cmd.Info{
-		Name:    "pool-list",
-		Usage:   "pool-list",
-		Desc:    "List all pools available for deploy.",
+		Name:  "pool-list",
+		Usage: "pool-list [--format table|json|yaml]",
+		Desc: `List all pools available for deploy.
+
+With [[--format]] json or yaml, the pools of each team and the public pools
+are shown in the same structure returned by the tsuru API.`,
 		MinArgs: 0,
 	}
 }
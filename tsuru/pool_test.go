@@ -6,11 +6,13 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/http"
 
 	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"gopkg.in/check.v1"
+	"gopkg.in/yaml.v1"
 )
 
 func (s *S) TestPoolListInfo(c *check.C) {
@@ -43,3 +45,48 @@ func (s *S) TestPoolListRun(c *check.C) {
 	c.Assert(err, check.IsNil)
 	c.Assert(stdout.String(), check.Equals, expected)
 }
+
+func (s *S) TestPoolListJSON(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /pools", http.StatusOK, `{"pools_by_team": [{"team": "test", "pools": ["pool1", "pool2"]}], "public_pools": [{"name": "public"}]}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := poolList{}
+	command.Flags().Parse(true, []string{"--format", "json"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var pools ListPoolResponse
+	err = json.Unmarshal(stdout.Bytes(), &pools)
+	c.Assert(err, check.IsNil)
+	c.Assert(pools, check.DeepEquals, ListPoolResponse{
+		PoolsByTeam: []PoolsByTeam{{Team: "test", Pools: []string{"pool1", "pool2"}}},
+		PublicPools: []Pool{{Name: "public"}},
+	})
+	c.Assert(stdout.String(), check.Matches, `(?s).*"pools_by_team".*"public_pools".*`)
+}
+
+func (s *S) TestPoolListYAML(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().on("GET /pools", http.StatusOK, `{"pools_by_team": [{"team": "test", "pools": ["pool"]}]}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := poolList{}
+	command.Flags().Parse(true, []string{"--format", "yaml"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var pools ListPoolResponse
+	err = yaml.Unmarshal(stdout.Bytes(), &pools)
+	c.Assert(err, check.IsNil)
+	c.Assert(pools.PoolsByTeam, check.DeepEquals, []PoolsByTeam{{Team: "test", Pools: []string{"pool"}}})
+	c.Assert(pools.PublicPools, check.HasLen, 0)
+	c.Assert(stdout.String(), check.Matches, `(?s).*pools_by_team:.*public_pools:.*`)
+}
+
+func (s *S) TestPoolListInvalidFormat(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	command := poolList{}
+	command.Flags().Parse(true, []string{"--format", "xml"})
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, `invalid format "xml", valid formats are: table, json, yaml`)
+}
@@ -0,0 +1,33 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "net"
+
+// dnsResolver resolves DNS names. It's an interface so tests don't depend on
+// the network.
+type dnsResolver interface {
+	LookupHost(host string) ([]string, error)
+	LookupCNAME(host string) (string, error)
+}
+
+type netResolver struct{}
+
+func (netResolver) LookupHost(host string) ([]string, error) {
+	return net.LookupHost(host)
+}
+
+func (netResolver) LookupCNAME(host string) (string, error) {
+	return net.LookupCNAME(host)
+}
+
+var dnsResolv dnsResolver
+
+func resolver() dnsResolver {
+	if dnsResolv == nil {
+		dnsResolv = netResolver{}
+	}
+	return dnsResolv
+}
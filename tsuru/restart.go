@@ -0,0 +1,145 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/cmd"
+)
+
+const (
+	defaultRollingTimeout = 5 * time.Minute
+	rollingPollInterval   = 2 * time.Second
+)
+
+// rollingRestart restarts the processes of the app in batches of c.batch
+// processes, waiting for the units of each batch to start before moving on.
+// The API restarts whole processes, so a process is the smallest unit of a
+// batch. Units without a process name can't be restarted on their own, so
+// apps that have them are refused.
+func (c *appRestart) rollingRestart(context *cmd.Context, client *cmd.Client, appName string) error {
+	if c.batch < 1 {
+		return errors.New("The --batch flag must be at least 1.")
+	}
+	a, err := getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	counts := unitCounts(a.Units)
+	if len(counts) == 0 {
+		return fmt.Errorf("app %q has no units to restart", appName)
+	}
+	if _, ok := counts[""]; ok {
+		return fmt.Errorf("app %q has units without a process name, they can't be restarted in batches, run app-restart without --rolling to restart the whole app", appName)
+	}
+	processes := make([]string, 0, len(counts))
+	for process := range counts {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+	var batches [][]string
+	for i := 0; i < len(processes); i += c.batch {
+		end := i + c.batch
+		if end > len(processes) {
+			end = len(processes)
+		}
+		batches = append(batches, processes[i:end])
+	}
+	for i, batch := range batches {
+		names := processNames(batch)
+		fmt.Fprintf(context.Stdout, "==> Batch %d/%d: restarting %s\n", i+1, len(batches), names)
+		err = c.restartBatch(context, client, appName, batch)
+		if err != nil {
+			fmt.Fprintf(context.Stdout, "Rolling restart of app %q aborted in batch %d/%d.\n", appName, i+1, len(batches))
+			var restarted, pending []string
+			for _, b := range batches[:i] {
+				restarted = append(restarted, b...)
+			}
+			for _, b := range batches[i+1:] {
+				pending = append(pending, b...)
+			}
+			fmt.Fprintf(context.Stdout, "Restarted: %s\n", processNames(restarted))
+			fmt.Fprintf(context.Stdout, "Failed: %s\n", names)
+			fmt.Fprintf(context.Stdout, "Not restarted: %s\n", processNames(pending))
+			return fmt.Errorf("rolling restart failed while restarting %s: %s", names, err)
+		}
+		if c.pause > 0 && i < len(batches)-1 {
+			fmt.Fprintf(context.Stdout, "Waiting %s before the next batch...\n", c.pause)
+			<-watchTick(c.pause)
+		}
+	}
+	fmt.Fprintf(context.Stdout, "Rolling restart of app %q finished: %d process(es) restarted.\n", appName, len(processes))
+	return nil
+}
+
+func (c *appRestart) restartBatch(context *cmd.Context, client *cmd.Client, appName string, batch []string) error {
+	for _, process := range batch {
+		err := restartApp(context.Stdout, client, appName, process)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(context.Stdout, "Waiting for the units of %s to start...\n", processNames(batch))
+	started, err := waitUnitsStarted(client, appName, batch, c.timeout)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(context.Stdout, "%d unit(s) started.\n", started)
+	return nil
+}
+
+// waitUnitsStarted polls the app until all the units of the given processes
+// are started, returning the number of units. It fails as soon as a unit is
+// in error, or when timeout has passed.
+func waitUnitsStarted(client *cmd.Client, appName string, processes []string, timeout time.Duration) (int, error) {
+	wanted := make(map[string]bool, len(processes))
+	for _, process := range processes {
+		wanted[process] = true
+	}
+	var elapsed time.Duration
+	for {
+		a, err := getApp(client, appName)
+		if err != nil {
+			return 0, err
+		}
+		var total, started int
+		var pending []string
+		for _, u := range a.Units {
+			if u.ID == "" || !wanted[u.ProcessName] {
+				continue
+			}
+			total++
+			switch {
+			case u.Available():
+				started++
+			case u.Status == "error":
+				return 0, fmt.Errorf("unit %s of process %s is in error", u.ID, u.ProcessName)
+			default:
+				pending = append(pending, fmt.Sprintf("%s is %s", u.ID, u.Status))
+			}
+		}
+		if started == total {
+			return total, nil
+		}
+		if elapsed >= timeout {
+			return 0, fmt.Errorf("timed out after %s waiting for units to start, %d of %d started (%s)",
+				timeout, started, total, strings.Join(pending, ", "))
+		}
+		<-watchTick(rollingPollInterval)
+		elapsed += rollingPollInterval
+	}
+}
+
+func processNames(processes []string) string {
+	if len(processes) == 0 {
+		return "none"
+	}
+	return strings.Join(processes, ", ")
+}
@@ -0,0 +1,149 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/tsuru/tsuru/cmd"
+	"gopkg.in/check.v1"
+)
+
+const (
+	rollingAppStarted = `{"name":"myapp","units":[
+{"ID":"w1","ProcessName":"web","Status":"started"},
+{"ID":"w2","ProcessName":"web","Status":"started"},
+{"ID":"k1","ProcessName":"worker","Status":"started"},
+{"ID":"c1","ProcessName":"clock","Status":"started"}]}`
+	rollingAppWebStarting = `{"name":"myapp","units":[
+{"ID":"w1","ProcessName":"web","Status":"started"},
+{"ID":"w2","ProcessName":"web","Status":"starting"},
+{"ID":"k1","ProcessName":"worker","Status":"started"},
+{"ID":"c1","ProcessName":"clock","Status":"started"}]}`
+	rollingAppWorkerError = `{"name":"myapp","units":[
+{"ID":"w1","ProcessName":"web","Status":"started"},
+{"ID":"w2","ProcessName":"web","Status":"started"},
+{"ID":"k1","ProcessName":"worker","Status":"error"},
+{"ID":"c1","ProcessName":"clock","Status":"started"}]}`
+)
+
+func (s *S) TestAppRestartRolling(c *check.C) {
+	var ticks []time.Duration
+	watchTick = func(d time.Duration) <-chan time.Time {
+		ticks = append(ticks, d)
+		return immediateWatchTick(d)
+	}
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, rollingAppStarted).
+		on("GET /apps/myapp", http.StatusOK, rollingAppWebStarting).
+		on("GET /apps/myapp", http.StatusOK, rollingAppStarted).
+		on("POST /apps/myapp/restart?process=clock", http.StatusOK, `{"Message":"clock restarted\n"}`).
+		on("POST /apps/myapp/restart?process=web", http.StatusOK, `{"Message":"web restarted\n"}`).
+		on("POST /apps/myapp/restart?process=worker", http.StatusOK, `{"Message":"worker restarted\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appRestart{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--rolling", "--batch", "2", "--pause", "30s"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/myapp",
+		"POST /apps/myapp/restart?process=clock",
+		"POST /apps/myapp/restart?process=web",
+		"GET /apps/myapp",
+		"GET /apps/myapp",
+		"POST /apps/myapp/restart?process=worker",
+		"GET /apps/myapp",
+	})
+	c.Assert(ticks, check.DeepEquals, []time.Duration{rollingPollInterval, 30 * time.Second})
+	expected := `==> Batch 1/2: restarting clock, web
+clock restarted
+web restarted
+Waiting for the units of clock, web to start...
+3 unit(s) started.
+Waiting 30s before the next batch...
+==> Batch 2/2: restarting worker
+worker restarted
+Waiting for the units of worker to start...
+1 unit(s) started.
+Rolling restart of app "myapp" finished: 3 process(es) restarted.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppRestartRollingAbortsOnFailedBatch(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, rollingAppStarted).
+		on("GET /apps/myapp", http.StatusOK, rollingAppStarted).
+		on("GET /apps/myapp", http.StatusOK, rollingAppStarted).
+		on("GET /apps/myapp", http.StatusOK, rollingAppWorkerError).
+		on("POST /apps/myapp/restart", http.StatusOK, `{"Message":"restarted\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appRestart{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--rolling"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "rolling restart failed while restarting worker: unit k1 of process worker is in error")
+	c.Assert(api.calls()[len(api.calls())-1], check.Equals, "GET /apps/myapp")
+	c.Assert(stdout.String(), check.Matches, `(?s).*Rolling restart of app "myapp" aborted in batch 3/3.
+Restarted: clock, web
+Failed: worker
+Not restarted: none
+`)
+}
+
+func (s *S) TestAppRestartRollingTimeout(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, rollingAppStarted).
+		on("GET /apps/myapp", http.StatusOK, rollingAppWebStarting).
+		on("POST /apps/myapp/restart", http.StatusOK, `{"Message":"restarted\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appRestart{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--rolling", "--batch", "3", "--timeout", "10s"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `rolling restart failed while restarting clock, web, worker: timed out after 10s waiting for units to start, 3 of 4 started \(w2 is starting\)`)
+	c.Assert(stdout.String(), check.Matches, `(?s).*Restarted: none
+Failed: clock, web, worker
+Not restarted: none
+`)
+}
+
+func (s *S) TestAppRestartRollingWithoutProcessName(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{Stdout: &stdout, Stderr: &stderr}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, `{"name":"myapp","units":[{"ID":"u1","ProcessName":"","Status":"started"},{"ID":"w1","ProcessName":"web","Status":"started"}]}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appRestart{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--rolling"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `app "myapp" has units without a process name, they can't be restarted in batches, run app-restart without --rolling to restart the whole app`)
+	c.Assert(api.calls(), check.DeepEquals, []string{"GET /apps/myapp"})
+}
+
+func (s *S) TestAppRestartRollingWithProcess(c *check.C) {
+	command := appRestart{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--rolling", "-p", "web"})
+	context := cmd.Context{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	err := command.Run(&context, nil)
+	c.Assert(err, check.ErrorMatches, "The --process and --rolling flags can't be used together.")
+}
@@ -5,9 +5,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/tsuru/tsuru/cmd"
@@ -17,9 +20,11 @@ import (
 
 type appRun struct {
 	cmd.GuessingCommand
-	fs   *gnuflag.FlagSet
-	once bool
+	fs          *gnuflag.FlagSet
+	once        bool
 	interactive bool
+	units       stringSliceValue
+	process     string
 }
 
 func (c *appRun) Info() *cmd.Info {
@@ -29,12 +34,24 @@ all commands is the root of the application.
 If you use the [[--once]] flag tsuru will run the command only in one unit.
 Otherwise, it will run the command in all units.
 
+The output of all units is shown as it's received. The API doesn't tell which
+unit produced each line, so the output can't be prefixed or split by unit.
+
+When the command fails, app-run fails with the error reported by the API,
+which includes the exit status of the command. The API stops at the first
+unit where the command fails and doesn't report the result of the other
+units, so only that failure is reported.
+
+The [[--unit]] and [[--process]] flags are reserved for running the command
+only in the given units or in the units of a process. The tsuru API can't run
+commands in chosen units yet, so they are refused for now.
+
 If you use the [[--interactive]] flag, tsuru will run the command in interactive mode.
 Note that --interactive implies --once=true.`
 
 	return &cmd.Info{
 		Name:    "app-run",
-		Usage:   "app-run <command> [commandarg1] [commandarg2] ... [commandargn] [-a/--app appname] [-o/--once] [-i/--interactive]",
+		Usage:   "app-run <command> [commandarg1] [commandarg2] ... [commandargn] [-a/--app appname] [-o/--once] [-i/--interactive] [--unit id]... [-p/--process name]",
 		Desc:    desc,
 		MinArgs: 1,
 	}
@@ -50,7 +67,11 @@ func (c *appRun) Run(context *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	b := strings.NewReader(strings.Join(context.Args, " "))
+	command := strings.Join(context.Args, " ")
+	if len(c.units) > 0 || c.process != "" {
+		return errors.New("The --unit and --process flags are not supported yet: the tsuru API can't run commands in chosen units.")
+	}
+	b := strings.NewReader(command)
 	request, err := http.NewRequest("POST", url, b)
 	if err != nil {
 		return err
@@ -64,7 +85,7 @@ func (c *appRun) Run(context *cmd.Context, client *cmd.Client) error {
 	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, r.Body) {
 	}
 	if err != nil {
-		return err
+		return &runExitError{status: exitStatusFromError(err.Error()), err: err}
 	}
 	unparsed := w.Remaining()
 	if len(unparsed) > 0 {
@@ -80,6 +101,45 @@ func (c *appRun) Flags() *gnuflag.FlagSet {
 		c.fs.BoolVar(&c.once, "o", false, "Running only one unit")
 		c.fs.BoolVar(&c.interactive, "interactive", false, "Running in interactive mode")
 		c.fs.BoolVar(&c.interactive, "i", false, "Running in interactive mode")
+		c.fs.Var(&c.units, "unit", "Run the command only in the given unit")
+		c.fs.StringVar(&c.process, "process", "", "Run the command only in the units of the given process")
+		c.fs.StringVar(&c.process, "p", "", "Run the command only in the units of the given process")
 	}
 	return c.fs
 }
+
+// stringSliceValue is a flag that may be given more than once.
+type stringSliceValue []string
+
+func (v *stringSliceValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+func (v *stringSliceValue) Set(value string) error {
+	*v = append(*v, value)
+	return nil
+}
+
+var exitStatusRegexp = regexp.MustCompile(`exit (?:status|code):? (\d+)`)
+
+// exitStatusFromError extracts the exit status of a remote command from an
+// error message sent by the API, defaulting to 1.
+func exitStatusFromError(message string) int {
+	if m := exitStatusRegexp.FindStringSubmatch(message); m != nil {
+		if status, err := strconv.Atoi(m[1]); err == nil && status > 0 {
+			return status
+		}
+	}
+	return 1
+}
+
+// runExitError is returned when the remote command fails, carrying its exit
+// status. The manager decides the exit status of the client.
+type runExitError struct {
+	status int
+	err    error
+}
+
+func (e *runExitError) Error() string {
+	return e.err.Error()
+}
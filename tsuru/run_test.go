@@ -137,3 +137,39 @@ func (s *S) TestAppRunInfo(c *check.C) {
 	command := appRun{}
 	c.Assert(command.Info(), check.NotNil)
 }
+
+func (s *S) TestAppRunExitStatusFromErrorMessage(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"false"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	result, _ := json.Marshal(io.SimpleJsonMessage{Error: "command failed: exit status 4"})
+	api := newStubAPI().on("POST /apps/myapp/run", http.StatusOK, string(result)+"\n")
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appRun{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "-i"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "command failed: exit status 4")
+	e, ok := err.(*runExitError)
+	c.Assert(ok, check.Equals, true)
+	c.Assert(e.status, check.Equals, 4)
+}
+
+func (s *S) TestExitStatusFromError(c *check.C) {
+	c.Assert(exitStatusFromError("exit status 3"), check.Equals, 3)
+	c.Assert(exitStatusFromError("Container exited with exit code: 127"), check.Equals, 127)
+	c.Assert(exitStatusFromError("something went wrong"), check.Equals, 1)
+	c.Assert(exitStatusFromError("exit status 0"), check.Equals, 1)
+}
+
+func (s *S) TestAppRunOnChosenUnits(c *check.C) {
+	context := cmd.Context{Args: []string{"ls"}, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	for _, args := range [][]string{{"--unit", "9e70748f4f25"}, {"-p", "web"}} {
+		command := appRun{}
+		command.Flags().Parse(true, append([]string{"-a", "myapp"}, args...))
+		err := command.Run(&context, nil)
+		c.Assert(err, check.ErrorMatches, "The --unit and --process flags are not supported yet: the tsuru API can't run commands in chosen units.")
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tsuru/tsuru/cmd"
+	"launchpad.net/gnuflag"
+)
+
+type appScale struct {
+	cmd.GuessingCommand
+	lockWaiter
+	fs     *gnuflag.FlagSet
+	dryRun bool
+}
+
+func (c *appScale) Info() *cmd.Info {
+	return &cmd.Info{
+		Name:  "app-scale",
+		Usage: "app-scale <process>=<units>... [-a/--app appname] [--dry-run] [--wait-lock 5m]",
+		Desc: `Sets the number of units of one or more processes of an app, for example:
+
+::
+
+    $ tsuru app-scale -a myapp web=4 worker=2
+
+The command compares the given numbers with the current units of each
+process, and adds or removes the difference. Units are added before any unit
+is removed. When it's done, it checks that the app has the requested number of
+units.
+
+The [[--dry-run]] flag shows what would be done, without changing the app.`,
+		MinArgs: 1,
+	}
+}
+
+func (c *appScale) Flags() *gnuflag.FlagSet {
+	if c.fs == nil {
+		c.fs = cmd.MergeFlagSet(c.GuessingCommand.Flags(), c.lockWaiter.Flags())
+		c.fs.BoolVar(&c.dryRun, "dry-run", false, "Show what would be done, without changing the app")
+	}
+	return c.fs
+}
+
+func (c *appScale) Run(context *cmd.Context, client *cmd.Client) error {
+	context.RawOutput()
+	desired, err := parseScaleArgs(context.Args)
+	if err != nil {
+		return err
+	}
+	appName, err := c.Guess()
+	if err != nil {
+		return err
+	}
+	err = c.waitLock(context.Stdout, client, appName)
+	if err != nil {
+		return err
+	}
+	a, err := getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	counts := unitCounts(a.Units)
+	processes := make([]string, 0, len(desired))
+	for process := range desired {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+	var toAdd, toRemove []string
+	for _, process := range processes {
+		switch {
+		case desired[process] > counts[process]:
+			toAdd = append(toAdd, process)
+		case desired[process] < counts[process]:
+			toRemove = append(toRemove, process)
+		default:
+			fmt.Fprintf(context.Stdout, "Process %s already has %d unit(s).\n", process, counts[process])
+		}
+	}
+	if c.dryRun {
+		for _, process := range append(toAdd, toRemove...) {
+			fmt.Fprintf(context.Stdout, "Would %s.\n", scaleDescription(process, counts[process], desired[process]))
+		}
+		return nil
+	}
+	if len(toAdd) == 0 && len(toRemove) == 0 {
+		return nil
+	}
+	for _, process := range toAdd {
+		fmt.Fprintf(context.Stdout, "==> %s\n", capitalize(scaleDescription(process, counts[process], desired[process])))
+		err = addUnits(context.Stdout, client, appName, strconv.Itoa(desired[process]-counts[process]), process)
+		if err != nil {
+			return err
+		}
+	}
+	for _, process := range toRemove {
+		fmt.Fprintf(context.Stdout, "==> %s\n", capitalize(scaleDescription(process, counts[process], desired[process])))
+		err = removeUnits(context.Stdout, client, appName, strconv.Itoa(counts[process]-desired[process]), process)
+		if err != nil {
+			return err
+		}
+	}
+	a, err = getApp(client, appName)
+	if err != nil {
+		return err
+	}
+	counts = unitCounts(a.Units)
+	var mismatches []string
+	for _, process := range processes {
+		if counts[process] != desired[process] {
+			mismatches = append(mismatches, fmt.Sprintf("%s has %d unit(s), expected %d", process, counts[process], desired[process]))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("app %q didn't reach the requested number of units: %s", appName, strings.Join(mismatches, "; "))
+	}
+	fmt.Fprintf(context.Stdout, "App %q scaled: %s.\n", appName, strings.Join(context.Args, " "))
+	return nil
+}
+
+// parseScaleArgs parses arguments in the form process=units.
+func parseScaleArgs(args []string) (map[string]int, error) {
+	desired := make(map[string]int, len(args))
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid argument %q, expected <process>=<units>", arg)
+		}
+		units, err := strconv.Atoi(parts[1])
+		if err != nil || units < 0 {
+			return nil, fmt.Errorf("invalid number of units for process %q: %q", parts[0], parts[1])
+		}
+		if _, ok := desired[parts[0]]; ok {
+			return nil, fmt.Errorf("process %q was given more than once", parts[0])
+		}
+		desired[parts[0]] = units
+	}
+	return desired, nil
+}
+
+func scaleDescription(process string, current, desired int) string {
+	if desired > current {
+		return fmt.Sprintf("add %d unit(s) to process %s (%d -> %d)", desired-current, process, current, desired)
+	}
+	return fmt.Sprintf("remove %d unit(s) from process %s (%d -> %d)", current-desired, process, current, desired)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
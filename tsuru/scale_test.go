@@ -0,0 +1,112 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/tsuru/tsuru/cmd"
+	"gopkg.in/check.v1"
+)
+
+const scaleAppBefore = `{"name":"myapp","units":[
+{"ID":"u1","ProcessName":"web","Status":"started"},
+{"ID":"u2","ProcessName":"worker","Status":"started"},
+{"ID":"u3","ProcessName":"worker","Status":"started"},
+{"ID":"u4","ProcessName":"worker","Status":"started"}]}`
+
+const scaleAppAfter = `{"name":"myapp","units":[
+{"ID":"u1","ProcessName":"web","Status":"started"},
+{"ID":"u5","ProcessName":"web","Status":"started"},
+{"ID":"u6","ProcessName":"web","Status":"started"},
+{"ID":"u2","ProcessName":"worker","Status":"started"}]}`
+
+func (s *S) TestAppScaleInfo(c *check.C) {
+	c.Assert((&appScale{}).Info(), check.NotNil)
+}
+
+func (s *S) TestAppScale(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"web=3", "worker=1", "clock=0"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, scaleAppBefore).
+		on("GET /apps/myapp", http.StatusOK, scaleAppAfter).
+		on("PUT /apps/myapp/units", http.StatusOK, `{"Message":"units added\n"}`).
+		on("DELETE /apps/myapp/units", http.StatusOK, `{"Message":"units removed\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appScale{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/myapp",
+		"PUT /apps/myapp/units",
+		"DELETE /apps/myapp/units?process=worker&units=2",
+		"GET /apps/myapp",
+	})
+	c.Assert(api.bodies[1], check.Matches, `.*units=2.*`)
+	c.Assert(api.bodies[1], check.Matches, `.*process=web.*`)
+	expected := `Process clock already has 0 unit(s).
+==> Add 2 unit(s) to process web (1 -> 3)
+units added
+==> Remove 2 unit(s) from process worker (3 -> 1)
+units removed
+App "myapp" scaled: web=3 worker=1 clock=0.
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppScaleDryRun(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"web=3", "worker=1"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	api := newStubAPI().on("GET /apps/myapp", http.StatusOK, scaleAppBefore)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appScale{}
+	command.Flags().Parse(true, []string{"-a", "myapp", "--dry-run"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.DeepEquals, []string{"GET /apps/myapp"})
+	expected := `Would add 2 unit(s) to process web (1 -> 3).
+Would remove 2 unit(s) from process worker (3 -> 1).
+`
+	c.Assert(stdout.String(), check.Equals, expected)
+}
+
+func (s *S) TestAppScaleFinalCountMismatch(c *check.C) {
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Args:   []string{"web=4"},
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	api := newStubAPI().
+		on("GET /apps/myapp", http.StatusOK, scaleAppBefore).
+		on("GET /apps/myapp", http.StatusOK, scaleAppAfter).
+		on("PUT /apps/myapp/units", http.StatusOK, `{"Message":"units added\n"}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appScale{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, `app "myapp" didn't reach the requested number of units: web has 3 unit\(s\), expected 4`)
+}
+
+func (s *S) TestAppScaleInvalidArgs(c *check.C) {
+	command := appScale{}
+	command.Flags().Parse(true, []string{"-a", "myapp"})
+	for _, args := range [][]string{{"web"}, {"web=-1"}, {"=2"}, {"web=two"}, {"web=1", "web=2"}} {
+		context := cmd.Context{Args: args, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+		err := command.Run(&context, nil)
+		c.Check(err, check.NotNil, check.Commentf("%v", args))
+	}
+}
@@ -139,7 +139,11 @@ func (sb *serviceBind) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	instanceName := ctx.Args[0]
+	return bindServiceInstance(ctx.Stdout, client, ctx.Args[0], appName)
+}
+
+// bindServiceInstance binds the app to the service instance, streaming the output of the API to out.
+func bindServiceInstance(out io.Writer, client *cmd.Client, instanceName, appName string) error {
 	url, err := cmd.GetURL("/services/instances/" + instanceName + "/" + appName)
 	if err != nil {
 		return err
@@ -153,7 +157,7 @@ func (sb *serviceBind) Run(ctx *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	defer resp.Body.Close()
-	w := tsuruIo.NewStreamWriter(ctx.Stdout, nil)
+	w := tsuruIo.NewStreamWriter(out, nil)
 	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, resp.Body) {
 	}
 	if err != nil {
@@ -190,7 +194,11 @@ func (su *serviceUnbind) Run(ctx *cmd.Context, client *cmd.Client) error {
 	if err != nil {
 		return err
 	}
-	instanceName := ctx.Args[0]
+	return unbindServiceInstance(ctx.Stdout, client, ctx.Args[0], appName)
+}
+
+// unbindServiceInstance unbinds the app from the service instance, streaming the output of the API to out.
+func unbindServiceInstance(out io.Writer, client *cmd.Client, instanceName, appName string) error {
 	url, err := cmd.GetURL("/services/instances/" + instanceName + "/" + appName)
 	if err != nil {
 		return err
@@ -204,7 +212,7 @@ func (su *serviceUnbind) Run(ctx *cmd.Context, client *cmd.Client) error {
 		return err
 	}
 	defer resp.Body.Close()
-	w := tsuruIo.NewStreamWriter(ctx.Stdout, nil)
+	w := tsuruIo.NewStreamWriter(out, nil)
 	for n := int64(1); n > 0 && err == nil; n, err = io.Copy(w, resp.Body) {
 	}
 	if err != nil {
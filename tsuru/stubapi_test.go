@@ -0,0 +1,84 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+type stubResponse struct {
+	status int
+	body   string
+}
+
+// stubAPI is a stub of the tsuru API for commands that make several different
+// requests. Responses are looked up by "METHOD /path?query" and then by
+// "METHOD /path", and unknown requests get a 404. A key may have a sequence
+// of responses, the last one is repeated. Every request is recorded, along
+// with its body.
+type stubAPI struct {
+	mut       sync.Mutex
+	responses map[string][]stubResponse
+	requests  []string
+	bodies    []string
+}
+
+func newStubAPI() *stubAPI {
+	return &stubAPI{responses: make(map[string][]stubResponse)}
+}
+
+func (a *stubAPI) on(key string, status int, body string) *stubAPI {
+	a.responses[key] = append(a.responses[key], stubResponse{status: status, body: body})
+	return a
+}
+
+func (a *stubAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	key := req.Method + " " + req.URL.Path
+	if req.URL.RawQuery != "" {
+		key += "?" + req.URL.RawQuery
+	}
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	a.requests = append(a.requests, key)
+	a.bodies = append(a.bodies, string(body))
+	if _, ok := a.responses[key]; !ok {
+		key = req.Method + " " + req.URL.Path
+	}
+	responses, ok := a.responses[key]
+	resp := stubResponse{status: http.StatusNotFound, body: "not found"}
+	if ok && len(responses) > 0 {
+		resp = responses[0]
+		if len(responses) > 1 {
+			a.responses[key] = responses[1:]
+		}
+	}
+	return &http.Response{
+		Body:       ioutil.NopCloser(bytes.NewBufferString(resp.body)),
+		StatusCode: resp.status,
+		Header:     http.Header{},
+	}, nil
+}
+
+func (a *stubAPI) calls() []string {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	return append([]string(nil), a.requests...)
+}
+
+// reset forgets the requests recorded so far.
+func (a *stubAPI) reset() {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.requests = nil
+	a.bodies = nil
+}
@@ -6,27 +6,44 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/tsuru/tsuru/cmd"
 	"github.com/tsuru/tsuru/errors"
 	"launchpad.net/gnuflag"
 )
 
+const (
+	defaultSwapTimeout = 2 * time.Minute
+	swapPollInterval   = 2 * time.Second
+)
+
 type appSwap struct {
 	cmd.Command
-	force bool
-	fs    *gnuflag.FlagSet
+	lockWaiter
+	force   bool
+	timeout time.Duration
+	fs      *gnuflag.FlagSet
 }
 
 func (s *appSwap) Info() *cmd.Info {
 	return &cmd.Info{
 		Name:  "app-swap",
-		Usage: "app-swap <app1-name> <app2-name> [-f/--force]",
+		Usage: "app-swap <app1-name> <app2-name> [-f/--force] [--timeout 2m] [--wait-lock 5m]",
 		Desc: `Swaps routing between two apps. This allows zero downtime and makes rollback
 as simple as swapping the applications back.
 
+Before swapping, the command shows both apps side by side, and warns about
+apps with units that are not started. The comparison is informative only, it
+doesn't stop the swap.
+
+After swapping, it waits up to [[--timeout]] for the addresses of both apps to
+serve requests, and prints the command that swaps them back.
+
 Use [[--force]] if you want to swap applications with a different number of
 units or different platform without confirmation.`,
 		MinArgs: 2,
@@ -38,12 +55,23 @@ func (s *appSwap) Flags() *gnuflag.FlagSet {
 		s.fs = gnuflag.NewFlagSet("", gnuflag.ExitOnError)
 		s.fs.BoolVar(&s.force, "force", false, "Force Swap among apps with different number of units or different platform.")
 		s.fs.BoolVar(&s.force, "f", false, "Force Swap among apps with different number of units or different platform.")
+		s.fs.DurationVar(&s.timeout, "timeout", defaultSwapTimeout, "Maximum time to wait for the apps to serve requests after the swap")
+		s.fs = cmd.MergeFlagSet(s.fs, s.lockWaiter.Flags())
 	}
 	return s.fs
 }
 
 func (s *appSwap) Run(context *cmd.Context, client *cmd.Client) error {
-	url, err := cmd.GetURL(fmt.Sprintf("/swap?app1=%s&app2=%s&force=%t", context.Args[0], context.Args[1], s.force))
+	app1, app2 := context.Args[0], context.Args[1]
+	apps := s.preflight(context.Stdout, client, app1, app2)
+	for _, name := range []string{app1, app2} {
+		err := s.waitLock(context.Stdout, client, name)
+		if err != nil {
+			return err
+		}
+	}
+	force := s.force
+	url, err := cmd.GetURL(fmt.Sprintf("/swap?app1=%s&app2=%s&force=%t", app1, app2, force))
 	if err != nil {
 		return err
 	}
@@ -53,19 +81,161 @@ func (s *appSwap) Run(context *cmd.Context, client *cmd.Client) error {
 			var answer string
 			fmt.Fprintf(context.Stdout, "WARNING: %s.\nSwap anyway? (y/n) ", strings.TrimRight(e.Message, "\n"))
 			fmt.Fscanf(context.Stdin, "%s", &answer)
-			if answer == "y" || answer == "yes" {
-				url, _ = cmd.GetURL(fmt.Sprintf("/swap?app1=%s&app2=%s&force=%t", context.Args[0], context.Args[1], true))
-				return makeSwap(client, url)
+			if answer != "y" && answer != "yes" {
+				fmt.Fprintln(context.Stdout, "swap aborted.")
+				return nil
 			}
-			fmt.Fprintln(context.Stdout, "swap aborted.")
-			return nil
+			force = true
+			url, _ = cmd.GetURL(fmt.Sprintf("/swap?app1=%s&app2=%s&force=%t", app1, app2, force))
+			err = makeSwap(client, url)
+		}
+		if err != nil {
+			return err
 		}
-		return err
 	}
 	fmt.Fprintln(context.Stdout, "Apps successfully swapped!")
+	err = s.waitServing(context.Stdout, apps)
+	swapBack := fmt.Sprintf("tsuru app-swap %s %s", app1, app2)
+	if force {
+		swapBack += " -f"
+	}
+	fmt.Fprintf(context.Stdout, "To swap them back, run: %s\n", swapBack)
 	return err
 }
 
+// preflight shows both apps side by side and warns about units that are
+// not started. It returns the apps, or nil when they couldn't be loaded.
+func (s *appSwap) preflight(w io.Writer, client *cmd.Client, app1, app2 string) []*app {
+	var apps []*app
+	var deploys []string
+	for _, name := range []string{app1, app2} {
+		a, err := getApp(client, name)
+		if err != nil {
+			fmt.Fprintf(w, "Warning: unable to load app %q for comparison: %s\n", name, err)
+			return nil
+		}
+		apps = append(apps, a)
+		deploy := "never"
+		if list, err := lastDeploys(client, name, 1); err == nil && len(list) > 0 {
+			deploy = list[0].Timestamp.Local().Format(time.Stamp)
+			if list[0].Image != "" {
+				deploy = fmt.Sprintf("%s (%s)", deploy, list[0].Image)
+			}
+		}
+		deploys = append(deploys, deploy)
+	}
+	table := cmd.NewTable()
+	table.Headers = cmd.Row{"", app1, app2}
+	rows := [][]string{
+		{"Platform", apps[0].Platform, apps[1].Platform},
+		{"Plan", apps[0].Plan.Name, apps[1].Plan.Name},
+		{"Units", formatUnitCounts(apps[0].Units), formatUnitCounts(apps[1].Units)},
+		{"Pool", apps[0].Pool, apps[1].Pool},
+		{"Cnames", strings.Join(apps[0].cnames(), ", "), strings.Join(apps[1].cnames(), ", ")},
+		{"Last deploy", deploys[0], deploys[1]},
+	}
+	var differ bool
+	for _, row := range rows {
+		if row[1] != row[2] && row[0] != "Cnames" && row[0] != "Last deploy" {
+			row[0] = "* " + row[0]
+			differ = true
+		}
+		table.AddRow(cmd.Row(row))
+	}
+	fmt.Fprint(w, table.String())
+	if differ {
+		fmt.Fprintln(w, "* differs between the apps")
+	}
+	var unhealthy []string
+	for _, a := range apps {
+		available, total := a.unitsSummary()
+		if total == 0 || available < total {
+			unhealthy = append(unhealthy, fmt.Sprintf("app %q has %d of %d units started", a.Name, available, total))
+		}
+	}
+	if len(unhealthy) > 0 {
+		fmt.Fprintf(w, "Warning: %s.\n", strings.Join(unhealthy, " and "))
+	}
+	return apps
+}
+
+// waitServing polls the addresses of the apps until all of them serve
+// requests, or the timeout passes.
+func (s *appSwap) waitServing(w io.Writer, apps []*app) error {
+	pending := make([]string, 0, len(apps))
+	for _, a := range apps {
+		if a.Ip != "" {
+			pending = append(pending, a.Ip)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "Waiting for %s to serve requests...\n", strings.Join(pending, " and "))
+	var elapsed time.Duration
+	for {
+		var failing []string
+		var lastErr error
+		for _, addr := range pending {
+			if err := swapProbe(addr); err != nil {
+				failing = append(failing, addr)
+				lastErr = err
+			} else {
+				fmt.Fprintf(w, "%s is serving requests.\n", addr)
+			}
+		}
+		pending = failing
+		if len(pending) == 0 {
+			return nil
+		}
+		if elapsed >= s.timeout {
+			return fmt.Errorf("timed out after %s waiting for %s to serve requests: %s", s.timeout, strings.Join(pending, " and "), lastErr)
+		}
+		<-watchTick(swapPollInterval)
+		elapsed += swapPollInterval
+	}
+}
+
+// swapProbe checks whether the given address serves HTTP requests. Tests
+// replace it.
+var swapProbe = probeAddress
+
+func probeAddress(addr string) error {
+	client := http.Client{Timeout: 5 * time.Second}
+	response, err := client.Get("http://" + addr + "/")
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 500 {
+		return fmt.Errorf("got status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// formatUnitCounts returns the number of units per process, as in
+// "web: 2, worker: 1".
+func formatUnitCounts(units []unit) string {
+	counts := unitCounts(units)
+	if len(counts) == 0 {
+		return "0"
+	}
+	processes := make([]string, 0, len(counts))
+	for process := range counts {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+	parts := make([]string, len(processes))
+	for i, process := range processes {
+		if process == "" {
+			parts[i] = fmt.Sprintf("%d", counts[process])
+		} else {
+			parts[i] = fmt.Sprintf("%s: %d", process, counts[process])
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
 func makeSwap(client *cmd.Client, url string) error {
 	request, err := http.NewRequest("PUT", url, nil)
 	if err != nil {
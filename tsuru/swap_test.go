@@ -6,10 +6,12 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/tsuru/tsuru/cmd"
-	"github.com/tsuru/tsuru/cmd/cmdtest"
 	"gopkg.in/check.v1"
 )
 
@@ -18,60 +20,199 @@ func (s *S) TestSwapInfo(c *check.C) {
 	c.Assert(command.Info(), check.NotNil)
 }
 
+const swapApp2 = `{"name":"app2","platform":"python","plan":{"name":"small"},"pool":"pool1","ip":"app2.tsuru.io",
+"units":[{"ID":"b1","ProcessName":"web","Status":"started"}]}`
+
+func swapStubAPI(app2 string) *stubAPI {
+	return newStubAPI().
+		on("GET /apps/app1", http.StatusOK, `{"name":"app1","platform":"python","plan":{"name":"small"},"pool":"pool1","ip":"app1.tsuru.io",
+"cname":["www.example.com"],"units":[{"ID":"a1","ProcessName":"web","Status":"started"},{"ID":"a2","ProcessName":"web","Status":"started"}]}`).
+		on("GET /apps/app2", http.StatusOK, app2).
+		on("GET /deploys?app=app1&limit=1", http.StatusNoContent, "").
+		on("GET /deploys?app=app2&limit=1", http.StatusNoContent, "")
+}
+
 func (s *S) TestSwap(c *check.C) {
 	var buf bytes.Buffer
-	var called bool
-	transport := cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{Status: http.StatusOK, Message: ""},
-		CondFunc: func(r *http.Request) bool {
-			called = true
-			return r.Method == "PUT" && r.URL.Path == "/swap"
-		},
+	var probed []string
+	swapProbe = func(addr string) error {
+		probed = append(probed, addr)
+		return nil
 	}
+	defer func() {
+		swapProbe = probeAddress
+	}()
+	api := swapStubAPI(swapApp2).on("PUT /swap", http.StatusOK, "")
 	context := cmd.Context{
 		Args:   []string{"app1", "app2"},
 		Stdout: &buf,
 	}
-	client := cmd.NewClient(&http.Client{Transport: &transport}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
 	command := appSwap{}
+	command.Flags().Parse(true, nil)
 	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
-	c.Assert(called, check.Equals, true)
-	expected := "Apps successfully swapped!\n"
+	c.Assert(api.calls(), check.DeepEquals, []string{
+		"GET /apps/app1",
+		"GET /deploys?app=app1&limit=1",
+		"GET /apps/app2",
+		"GET /deploys?app=app2&limit=1",
+		"PUT /swap?app1=app1&app2=app2&force=false",
+	})
+	c.Assert(probed, check.DeepEquals, []string{"app1.tsuru.io", "app2.tsuru.io"})
+	expected := `+-------------+-----------------+--------+
+|             | app1            | app2   |
++-------------+-----------------+--------+
+| Platform    | python          | python |
+| Plan        | small           | small  |
+| * Units     | web: 2          | web: 1 |
+| Pool        | pool1           | pool1  |
+| Cnames      | www.example.com |        |
+| Last deploy | never           | never  |
++-------------+-----------------+--------+
+* differs between the apps
+Apps successfully swapped!
+Waiting for app1.tsuru.io and app2.tsuru.io to serve requests...
+app1.tsuru.io is serving requests.
+app2.tsuru.io is serving requests.
+To swap them back, run: tsuru app-swap app1 app2
+`
 	c.Assert(buf.String(), check.Equals, expected)
 }
 
 func (s *S) TestSwapWhenAppsAreNotEqual(c *check.C) {
 	var buf bytes.Buffer
-	var called int
+	swapProbe = func(string) error { return nil }
+	defer func() {
+		swapProbe = probeAddress
+	}()
 	stdin := bytes.NewBufferString("yes")
-	transportError := cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{Status: http.StatusPreconditionFailed, Message: "Apps are not equal."},
-		CondFunc: func(r *http.Request) bool {
-			called += 1
-			return r.URL.RawQuery == "app1=app1&app2=app2&force=false"
-		},
+	api := swapStubAPI(swapApp2).
+		on("PUT /swap?app1=app1&app2=app2&force=false", http.StatusPreconditionFailed, "Apps are not equal.").
+		on("PUT /swap?app1=app1&app2=app2&force=true", http.StatusOK, "")
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &buf,
+		Stdin:  stdin,
+	}
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appSwap{}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	calls := api.calls()
+	c.Assert(calls[len(calls)-2:], check.DeepEquals, []string{
+		"PUT /swap?app1=app1&app2=app2&force=false",
+		"PUT /swap?app1=app1&app2=app2&force=true",
+	})
+	c.Assert(buf.String(), check.Matches, `(?s).*WARNING: Apps are not equal.*\nSwap anyway\? \(y/n\) Apps successfully swapped!\n.*`)
+	c.Assert(buf.String(), check.Matches, `(?s).*To swap them back, run: tsuru app-swap app1 app2 -f\n`)
+}
+
+func (s *S) TestSwapWhenAppsAreNotEqualAborted(c *check.C) {
+	var buf bytes.Buffer
+	api := swapStubAPI(swapApp2).
+		on("PUT /swap?app1=app1&app2=app2&force=false", http.StatusPreconditionFailed, "Apps are not equal.")
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &buf,
+		Stdin:  strings.NewReader("n\n"),
+	}
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appSwap{}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	calls := api.calls()
+	c.Assert(calls[len(calls)-1], check.Equals, "PUT /swap?app1=app1&app2=app2&force=false")
+	c.Assert(buf.String(), check.Matches, `(?s).*Swap anyway\? \(y/n\) swap aborted.\n`)
+}
+
+func (s *S) TestSwapWithForceDoesNotAsk(c *check.C) {
+	var buf bytes.Buffer
+	swapProbe = func(string) error { return nil }
+	defer func() {
+		swapProbe = probeAddress
+	}()
+	api := swapStubAPI(swapApp2).on("PUT /swap?app1=app1&app2=app2&force=true", http.StatusOK, "")
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &buf,
 	}
-	transportOk := cmdtest.ConditionalTransport{
-		Transport: cmdtest.Transport{Status: http.StatusOK, Message: ""},
-		CondFunc: func(r *http.Request) bool {
-			called += 1
-			return r.URL.RawQuery == "app1=app1&app2=app2&force=true"
-		},
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appSwap{}
+	command.Flags().Parse(true, []string{"-f"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	calls := api.calls()
+	c.Assert(calls[len(calls)-1], check.Equals, "PUT /swap?app1=app1&app2=app2&force=true")
+	c.Assert(strings.Contains(buf.String(), "(y/n)"), check.Equals, false)
+}
+
+func (s *S) TestSwapTimesOutWaitingForAddresses(c *check.C) {
+	var buf bytes.Buffer
+	swapProbe = func(addr string) error {
+		if addr == "app2.tsuru.io" {
+			return errors.New("connection refused")
+		}
+		return nil
 	}
-	multiTransport := cmdtest.MultiConditionalTransport{
-		ConditionalTransports: []cmdtest.ConditionalTransport{transportError, transportOk},
+	watchTick = immediateWatchTick
+	defer func() {
+		swapProbe = probeAddress
+		watchTick = time.After
+	}()
+	api := swapStubAPI(swapApp2).on("PUT /swap", http.StatusOK, "")
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &buf,
+	}
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appSwap{}
+	command.Flags().Parse(true, []string{"--timeout", "10s"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.ErrorMatches, "timed out after 10s waiting for app2.tsuru.io to serve requests: connection refused")
+	c.Assert(buf.String(), check.Matches, `(?s).*To swap them back, run: tsuru app-swap app1 app2\n`)
+}
+
+func (s *S) TestSwapWarnsAboutUnhealthyApps(c *check.C) {
+	var buf bytes.Buffer
+	swapProbe = func(string) error { return nil }
+	defer func() {
+		swapProbe = probeAddress
+	}()
+	api := swapStubAPI(`{"name":"app2","units":[{"ID":"b1","ProcessName":"web","Status":"error"}]}`).
+		on("PUT /swap", http.StatusOK, "")
+	context := cmd.Context{
+		Args:   []string{"app1", "app2"},
+		Stdout: &buf,
 	}
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appSwap{}
+	command.Flags().Parse(true, nil)
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	calls := api.calls()
+	c.Assert(calls[len(calls)-1], check.Equals, "PUT /swap?app1=app1&app2=app2&force=false")
+	c.Assert(buf.String(), check.Matches, `(?s).*Warning: app "app2" has 0 of 1 units started.\n.*`)
+}
+
+func (s *S) TestSwapAppNotFound(c *check.C) {
+	var buf bytes.Buffer
+	api := newStubAPI().
+		on("GET /apps/app1", http.StatusNotFound, "App app1 not found.").
+		on("PUT /swap", http.StatusOK, "")
 	context := cmd.Context{
 		Args:   []string{"app1", "app2"},
 		Stdout: &buf,
-		Stdin:  stdin,
 	}
-	client := cmd.NewClient(&http.Client{Transport: &multiTransport}, nil, manager)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
 	command := appSwap{}
+	command.Flags().Parse(true, nil)
 	err := command.Run(&context, client)
 	c.Assert(err, check.IsNil)
-	c.Assert(called, check.Equals, 2)
+	c.Assert(api.calls(), check.DeepEquals, []string{"GET /apps/app1", "PUT /swap?app1=app1&app2=app2&force=false"})
+	c.Assert(buf.String(), check.Matches, `(?s)Warning: unable to load app "app1" for comparison: .*\nApps successfully swapped!\n.*`)
 }
 
 func (s *S) TestSwapIsACommand(c *check.C) {
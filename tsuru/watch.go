@@ -0,0 +1,153 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/tsuru/tsuru/cmd"
+)
+
+const (
+	clearScreen          = "\033[H\033[2J"
+	defaultWatchInterval = 2 * time.Second
+)
+
+// watchTick returns a channel that receives a value once the given interval
+// has passed. Tests replace it to avoid waiting between refreshes.
+var watchTick = time.After
+
+// runWatch calls refresh and redraws the screen with its output every
+// interval, until the user hits Ctrl-C. When untilHealthy is true, it also
+// returns as soon as refresh reports that all units are healthy.
+//
+// Only an error in the first refresh ends the watch, later errors are shown
+// below the last successful output and the refresh is retried in the next
+// tick.
+func runWatch(context *cmd.Context, interval time.Duration, untilHealthy bool, refresh func(w io.Writer) (bool, error)) error {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	var last []byte
+	for first := true; ; first = false {
+		var buf bytes.Buffer
+		healthy, err := refresh(&buf)
+		if err != nil && first {
+			return err
+		}
+		if err == nil {
+			last = buf.Bytes()
+		}
+		fmt.Fprint(context.Stdout, clearScreen)
+		fmt.Fprintf(context.Stdout, "Every %s, press Ctrl-C to exit. Statuses marked with * changed in the last refresh.\n\n", interval)
+		context.Stdout.Write(last)
+		if err != nil {
+			fmt.Fprintf(context.Stdout, "\nUnable to refresh, retrying in %s: %s\n", interval, err)
+		} else if untilHealthy && healthy {
+			fmt.Fprintln(context.Stdout, "All units are started.")
+			return nil
+		}
+		select {
+		case <-interrupt:
+			fmt.Fprintln(context.Stdout)
+			return nil
+		case <-watchTick(interval):
+		}
+	}
+}
+
+// unitsHealthy reports whether there is at least one unit and all of them
+// are available.
+func unitsHealthy(units []unit) bool {
+	var found bool
+	for _, u := range units {
+		if u.ID == "" {
+			continue
+		}
+		if !u.Available() {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+type watchedUnit struct {
+	status    string
+	changed   time.Time
+	changedIn int
+}
+
+// unitWatcher keeps track of the status of units across the refreshes of a
+// watch, so commands can tell which units changed and when.
+type unitWatcher struct {
+	units   map[string]*watchedUnit
+	refresh int
+}
+
+func newUnitWatcher() *unitWatcher {
+	return &unitWatcher{units: make(map[string]*watchedUnit)}
+}
+
+// update records the current status of the given units. It must be called
+// once per refresh, with all the units that are displayed.
+func (w *unitWatcher) update(units []unit) {
+	w.refresh++
+	now := time.Now()
+	for _, u := range units {
+		if u.ID == "" {
+			continue
+		}
+		watched, ok := w.units[u.ID]
+		if !ok {
+			w.units[u.ID] = &watchedUnit{status: u.Status}
+			continue
+		}
+		if watched.status != u.Status {
+			watched.status = u.Status
+			watched.changed = now
+			watched.changedIn = w.refresh
+		}
+	}
+}
+
+// render writes a table with the units whose status changed since the watch
+// started, and the time since each change. Units that changed in the last
+// refresh are marked with "*". The marker is plain text, as colors would
+// break the alignment of tables. Nothing is written before the first change.
+func (w *unitWatcher) render(out io.Writer) {
+	ids := make([]string, 0, len(w.units))
+	for id, watched := range w.units {
+		if !watched.changed.IsZero() {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Strings(ids)
+	table := cmd.NewTable()
+	table.Headers = cmd.Row([]string{"Unit", "Status", "Changed"})
+	for _, id := range ids {
+		watched := w.units[id]
+		status := watched.status
+		if watched.changedIn == w.refresh {
+			status = "* " + status
+		}
+		elapsed := time.Since(watched.changed) / time.Second * time.Second
+		table.AddRow(cmd.Row([]string{id, status, fmt.Sprintf("%s ago", elapsed)}))
+	}
+	fmt.Fprintln(out, "\nChanged units:")
+	out.Write(table.Bytes())
+}
@@ -0,0 +1,179 @@
+// Copyright 2015 tsuru-client authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tsuru/tsuru/cmd"
+	"gopkg.in/check.v1"
+)
+
+func immediateWatchTick(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func (s *S) TestUnitWatcher(c *check.C) {
+	watcher := newUnitWatcher()
+	var buf bytes.Buffer
+	watcher.update([]unit{{ID: "app1/0", Status: "building"}, {ID: "app1/1", Status: "started"}})
+	watcher.render(&buf)
+	c.Assert(buf.String(), check.Equals, "")
+	watcher.update([]unit{{ID: "app1/0", Status: "started"}, {ID: "app1/1", Status: "started"}})
+	watcher.render(&buf)
+	expected := `
+Changed units:
++--------+-----------+---------+
+| Unit   | Status    | Changed |
++--------+-----------+---------+
+| app1/0 | * started | 0s ago  |
++--------+-----------+---------+
+`
+	c.Assert(buf.String(), check.Equals, expected)
+	buf.Reset()
+	watcher.update([]unit{{ID: "app1/0", Status: "started"}, {ID: "app1/1", Status: "started"}})
+	watcher.render(&buf)
+	c.Assert(strings.Contains(buf.String(), "| app1/0 | started | 0s ago  |"), check.Equals, true)
+}
+
+func (s *S) TestUnitsHealthy(c *check.C) {
+	c.Assert(unitsHealthy([]unit{{ID: "app1/0", Status: "started"}, {Status: "pending"}}), check.Equals, true)
+	c.Assert(unitsHealthy([]unit{{ID: "app1/0", Status: "started"}, {ID: "app1/1", Status: "error"}}), check.Equals, false)
+	c.Assert(unitsHealthy(nil), check.Equals, false)
+	c.Assert(unitsHealthy([]unit{{Status: "pending"}}), check.Equals, false)
+}
+
+func (s *S) TestAppInfoWatchUntilHealthy(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	api := newStubAPI().
+		on("GET /docker/node/apps/app1/containers", http.StatusOK, `[]`).
+		on("GET /services/instances?app=app1", http.StatusOK, `[]`).
+		on("GET /apps/app1", http.StatusOK, `{"name":"app1","ip":"myapp.tsuru.io","platform":"php","units":[{"ID":"app1/0","Status":"pending"}]}`).
+		on("GET /apps/app1", http.StatusOK, `{"name":"app1","ip":"myapp.tsuru.io","platform":"php","units":[{"ID":"app1/0","Status":"started"}]}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1", "--until-healthy", "--interval", "1s"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	var calls int
+	for _, call := range api.calls() {
+		if call == "GET /apps/app1" {
+			calls++
+		}
+	}
+	c.Assert(calls, check.Equals, 2)
+	screens := strings.Split(stdout.String(), clearScreen)
+	c.Assert(screens, check.HasLen, 3)
+	c.Assert(screens[1], check.Matches, "(?s)Every 1s, press Ctrl-C to exit. Statuses marked with \\* changed in the last refresh.\n\nApplication: app1\n.*app1/0 \\| pending .*")
+	c.Assert(strings.Contains(screens[1], "Changed units:"), check.Equals, false)
+	c.Assert(screens[2], check.Matches, "(?s).*Changed units:\n.*\\| app1/0 \\| \\* started \\| 0s ago  \\|.*")
+	c.Assert(strings.HasSuffix(screens[2], "All units are started.\n"), check.Equals, true)
+}
+
+func (s *S) TestAppListWatchUntilHealthy(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	api := newStubAPI().
+		on("GET /apps", http.StatusOK, `[{"ip":"10.10.10.10","name":"app1","units":[{"ID":"app1/0","Status":"started"},{"ID":"app1/1","Status":"error"}]}]`).
+		on("GET /apps", http.StatusOK, `[{"ip":"10.10.10.10","name":"app1","units":[{"ID":"app1/0","Status":"started"},{"ID":"app1/1","Status":"started"}]}]`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appList{}
+	command.Flags().Parse(true, []string{"--until-healthy"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	c.Assert(api.calls(), check.HasLen, 2)
+	screens := strings.Split(stdout.String(), clearScreen)
+	c.Assert(screens, check.HasLen, 3)
+	c.Assert(strings.Contains(screens[1], "1 of 2 units in-service"), check.Equals, true)
+	c.Assert(strings.Contains(screens[2], "2 of 2 units in-service"), check.Equals, true)
+	c.Assert(strings.Contains(screens[2], "| app1/1 | * started | 0s ago  |"), check.Equals, true)
+	c.Assert(strings.HasSuffix(screens[2], "All units are started.\n"), check.Equals, true)
+}
+
+func (s *S) TestAppInfoWatchUntilHealthyWithoutUnits(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout, stderr bytes.Buffer
+	context := cmd.Context{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	api := newStubAPI().
+		on("GET /docker/node/apps/app1/containers", http.StatusOK, `[]`).
+		on("GET /services/instances?app=app1", http.StatusOK, `[]`).
+		on("GET /apps/app1", http.StatusOK, `{"name":"app1","units":[]}`).
+		on("GET /apps/app1", http.StatusOK, `{"name":"app1","units":[{"ID":"app1/0","Status":"started"}]}`)
+	client := cmd.NewClient(&http.Client{Transport: api}, nil, manager)
+	command := appInfo{}
+	command.Flags().Parse(true, []string{"--app", "app1", "--until-healthy"})
+	err := command.Run(&context, client)
+	c.Assert(err, check.IsNil)
+	screens := strings.Split(stdout.String(), clearScreen)
+	c.Assert(screens, check.HasLen, 3)
+	c.Assert(strings.HasSuffix(screens[1], "All units are started.\n"), check.Equals, false)
+}
+
+func (s *S) TestRunWatchRetriesAfterError(c *check.C) {
+	watchTick = immediateWatchTick
+	defer func() {
+		watchTick = time.After
+	}()
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	var calls int
+	err := runWatch(&context, time.Second, true, func(w io.Writer) (bool, error) {
+		calls++
+		switch calls {
+		case 1:
+			fmt.Fprintln(w, "first")
+			return false, nil
+		case 2:
+			return false, errors.New("connection refused")
+		}
+		fmt.Fprintln(w, "third")
+		return true, nil
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(calls, check.Equals, 3)
+	screens := strings.Split(stdout.String(), clearScreen)
+	c.Assert(screens, check.HasLen, 4)
+	c.Assert(screens[2], check.Matches, "(?s).*first\n\nUnable to refresh, retrying in 1s: connection refused\n")
+	c.Assert(screens[3], check.Matches, "(?s).*third\nAll units are started.\n")
+}
+
+func (s *S) TestRunWatchFailsOnFirstError(c *check.C) {
+	var stdout bytes.Buffer
+	context := cmd.Context{Stdout: &stdout}
+	err := runWatch(&context, time.Second, true, func(w io.Writer) (bool, error) {
+		return false, errors.New("app not found")
+	})
+	c.Assert(err, check.ErrorMatches, "app not found")
+	c.Assert(stdout.String(), check.Equals, "")
+}